@@ -0,0 +1,61 @@
+package aggregator
+
+import (
+	"sync"
+)
+
+// aggregationQuarantine tracks the consecutive aggregation failures of each
+// pair of proofs, keyed by the batch range they cover, and keeps the pairs
+// that reach the maximum allowed out of the work dispatch.
+type aggregationQuarantine struct {
+	maxFailures int
+
+	mu          sync.Mutex
+	failures    map[[2]uint64]int
+	quarantined map[[2]uint64]struct{}
+}
+
+func newAggregationQuarantine(maxFailures int) *aggregationQuarantine {
+	return &aggregationQuarantine{
+		maxFailures: maxFailures,
+		failures:    make(map[[2]uint64]int),
+		quarantined: make(map[[2]uint64]struct{}),
+	}
+}
+
+// recordFailure counts a failure aggregating the batch range. It returns true
+// if the range has been quarantined because of it.
+func (q *aggregationQuarantine) recordFailure(batchNumber, batchNumberFinal uint64) bool {
+	if q.maxFailures <= 0 {
+		return false
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	key := [2]uint64{batchNumber, batchNumberFinal}
+	q.failures[key]++
+	if q.failures[key] < q.maxFailures {
+		return false
+	}
+	delete(q.failures, key)
+	q.quarantined[key] = struct{}{}
+	return true
+}
+
+// recordSuccess resets the consecutive failures of the batch range.
+func (q *aggregationQuarantine) recordSuccess(batchNumber, batchNumberFinal uint64) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	delete(q.failures, [2]uint64{batchNumber, batchNumberFinal})
+}
+
+// isQuarantined returns true if the batch range must not be aggregated.
+func (q *aggregationQuarantine) isQuarantined(batchNumber, batchNumberFinal uint64) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	_, ok := q.quarantined[[2]uint64{batchNumber, batchNumberFinal}]
+	return ok
+}
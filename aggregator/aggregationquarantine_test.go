@@ -0,0 +1,33 @@
+package aggregator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAggregationQuarantine(t *testing.T) {
+	assert := assert.New(t)
+	q := newAggregationQuarantine(3)
+
+	assert.False(q.recordFailure(1, 4))
+	assert.False(q.recordFailure(1, 4))
+	q.recordSuccess(1, 4)
+	assert.False(q.recordFailure(1, 4))
+	assert.False(q.recordFailure(1, 4))
+	assert.False(q.recordFailure(5, 8))
+	assert.False(q.isQuarantined(1, 4))
+
+	assert.True(q.recordFailure(1, 4))
+	assert.True(q.isQuarantined(1, 4))
+	assert.False(q.isQuarantined(5, 8))
+}
+
+func TestAggregationQuarantineDisabled(t *testing.T) {
+	q := newAggregationQuarantine(0)
+
+	for i := 0; i < 10; i++ {
+		assert.False(t, q.recordFailure(1, 4))
+	}
+	assert.False(t, q.isQuarantined(1, 4))
+}
@@ -1,15 +1,19 @@
 package aggregator
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"math"
 	"math/big"
 	"net"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 	"unicode"
 
@@ -18,15 +22,20 @@ import (
 	"github.com/0xPolygonHermez/zkevm-node/aggregator/prover"
 	"github.com/0xPolygonHermez/zkevm-node/config/types"
 	"github.com/0xPolygonHermez/zkevm-node/encoding"
+	ethman "github.com/0xPolygonHermez/zkevm-node/etherman"
 	ethmanTypes "github.com/0xPolygonHermez/zkevm-node/etherman/types"
 	"github.com/0xPolygonHermez/zkevm-node/ethtxmanager"
 	"github.com/0xPolygonHermez/zkevm-node/log"
 	"github.com/0xPolygonHermez/zkevm-node/state"
 	"github.com/ethereum/go-ethereum/common"
+	ethTypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/google/uuid"
 	"github.com/jackc/pgx/v4"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	grpchealth "google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
 )
 
 const (
@@ -34,9 +43,55 @@ const (
 	mockedLocalExitRoot = "0x17c04c3760510b48c6012742c540a81aba4bca2f78b9d14bfd2f123e2e53ea3e"
 
 	ethTxManagerOwner = "aggregator"
-	monitoredIDFormat = "proof-from-%v-to-%v"
+	monitoredIDFormat = "proof-from-%s-to-%s"
+	// monitoredIDBatchNumberBase is the base the batch numbers are encoded
+	// with in the monitored tx IDs.
+	monitoredIDBatchNumberBase = encoding.Base10
+
+	stateLockPollInterval = 10 * time.Millisecond
+	// unlockProofsRetryInterval is the wait before the first retry releasing
+	// the proofs to aggregate, doubled on every following retry.
+	unlockProofsRetryInterval = 100 * time.Millisecond
 )
 
+// errInvalidProof is returned when the prover returns a recursive proof that
+// is not valid JSON.
+var errInvalidProof = errors.New("prover returned an invalid JSON proof")
+
+// errEmptyProof is returned when the prover reports a recursive proof as
+// generated but returns it empty.
+var errEmptyProof = errors.New("prover returned an empty proof")
+
+// proverError wraps the errors returned by a prover call, or about the proof
+// it returned, to tell them apart from the errors of the state or L1.
+type proverError struct {
+	err error
+}
+
+func (e *proverError) Error() string { return e.err.Error() }
+
+func (e *proverError) Unwrap() error { return e.err }
+
+// errStateLockTimeout is returned when the StateDBMutex can't be acquired
+// within the configured StateLockTimeout. The operation can be retried.
+var errStateLockTimeout = errors.New("timeout acquiring the state lock")
+
+// errInsufficientSenderBalance is returned when the sender has less than
+// MinSenderBalance to pay for the batch verification txs.
+var errInsufficientSenderBalance = errors.New("insufficient gas balance")
+
+// errGenesisBatch is returned when trying to prove the genesis batch, which
+// has no previous batch.
+var errGenesisBatch = errors.New("the genesis batch can't be proved")
+
+// errFinalBatchNotSynced is returned when the last batch of a proof to verify
+// is not synced in the state yet, deferring its verification.
+var errFinalBatchNotSynced = errors.New("final batch of the proof not synced")
+
+// errAccInputHashMismatch is returned when the accumulated input hash of a
+// batch in the state doesn't match the one sequenced on L1.
+var errAccInputHashMismatch = errors.New("accumulated input hash doesn't match L1")
+
 type finalProofMsg struct {
 	proverName     string
 	proverID       string
@@ -62,6 +117,26 @@ type Aggregator struct {
 	finalProof     chan finalProofMsg
 	verifyingProof bool
 
+	// fatalErr receives the unrecoverable errors of the background
+	// goroutines, making Start return.
+	fatalErr chan error
+
+	connectedProvers int32
+	paused           int32
+	// lowSenderBalance is set while the sender balance is below the
+	// MinSenderBalance, to warn about it only once
+	lowSenderBalance int32
+	proverBlocklist  *proverBlocklist
+	l1Breaker        *l1Breaker
+	proverLocks      *proverLocks
+	finalProofWait   *finalProofWait
+	stateAheadOfL1   *stateAheadOfL1
+	staleProofs      *staleProofs
+	batchCache       *batchCache
+	aggrQuarantine   *aggregationQuarantine
+
+	clock clock
+
 	srv  *grpc.Server
 	ctx  context.Context
 	exit context.CancelFunc
@@ -74,6 +149,21 @@ func New(
 	ethTxManager ethTxManager,
 	etherman etherman,
 ) (Aggregator, error) {
+	if err := cfg.Validate(); err != nil {
+		return Aggregator{}, fmt.Errorf("invalid aggregator config, %w", err)
+	}
+
+	// try the batch proof priorities from the highest to the lowest
+	cfg.BatchProofPriorities = append([]BatchProofPriority{}, cfg.BatchProofPriorities...)
+	sort.SliceStable(cfg.BatchProofPriorities, func(i, j int) bool {
+		return cfg.BatchProofPriorities[i].Priority > cfg.BatchProofPriorities[j].Priority
+	})
+
+	var cachedBatches int
+	if cfg.EnableBatchCache {
+		cachedBatches = batchCacheSize
+	}
+
 	var profitabilityChecker aggregatorTxProfitabilityChecker
 	switch cfg.TxProfitabilityCheckerType {
 	case ProfitabilityBase:
@@ -93,7 +183,19 @@ func New(
 		TimeSendFinalProofMutex: &sync.RWMutex{},
 		TimeCleanupLockedProofs: cfg.CleanupLockedProofsInterval,
 
+		proverBlocklist: newProverBlocklist(cfg.ProverMaxConsecutiveFailures, cfg.ProverBlocklistDuration.Duration),
+		l1Breaker:       newL1Breaker(cfg.L1FailureThreshold, cfg.L1BreakerCooldown.Duration),
+		proverLocks:     newProverLocks(cfg.MaxLockedBatchesPerProver),
+		finalProofWait:  &finalProofWait{},
+		stateAheadOfL1:  &stateAheadOfL1{},
+		staleProofs:     newStaleProofs(),
+		batchCache:      newBatchCache(cachedBatches),
+		aggrQuarantine:  newAggregationQuarantine(cfg.MaxAggregationRetries),
+
+		clock: realClock{},
+
 		finalProof: make(chan finalProofMsg),
+		fatalErr:   make(chan error, 1),
 	}
 
 	return a, nil
@@ -116,10 +218,14 @@ func (a *Aggregator) Start(ctx context.Context) error {
 		a.handleMonitoredTxResult(result)
 	}, nil)
 
-	// Delete ungenerated recursive proofs
-	err := a.State.DeleteUngeneratedProofs(ctx, nil)
+	err := a.cleanupUngeneratedProofs(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to initialize proofs cache %w", err)
+		return err
+	}
+
+	err = a.verifyStoredProofs(ctx)
+	if err != nil {
+		return err
 	}
 
 	address := fmt.Sprintf("%s:%d", a.cfg.Host, a.cfg.Port)
@@ -137,8 +243,7 @@ func (a *Aggregator) Start(ctx context.Context) error {
 	go func() {
 		log.Infof("Server listening on port %d", a.cfg.Port)
 		if err := a.srv.Serve(lis); err != nil {
-			a.exit()
-			log.Fatalf("Failed to serve: %v", err)
+			a.reportFatal(fmt.Errorf("failed to serve, %w", err))
 		}
 	}()
 
@@ -146,9 +251,67 @@ func (a *Aggregator) Start(ctx context.Context) error {
 
 	go a.cleanupLockedProofs()
 	go a.sendFinalProof()
+	go a.warnNoProvers()
+	go a.monitorOldestUnprovenBatch()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case err := <-a.fatalErr:
+		a.exit()
+		return err
+	}
+}
+
+// reportFatal surfaces an unrecoverable error from a background goroutine,
+// making Start return it. Only the first reported error is kept.
+func (a *Aggregator) reportFatal(err error) {
+	log.Error(FirstToUpper(err.Error()))
+	select {
+	case a.fatalErr <- err:
+	default:
+	}
+}
 
-	<-ctx.Done()
-	return ctx.Err()
+// cleanupUngeneratedProofs deletes the recursive proofs left in generating
+// state by a previous run, unless disabled by configuration.
+func (a *Aggregator) cleanupUngeneratedProofs(ctx context.Context) error {
+	if !a.cfg.CleanupUngeneratedOnStart {
+		log.Info("Skipping cleanup of ungenerated proofs on startup")
+		return nil
+	}
+
+	// Delete ungenerated recursive proofs
+	err := a.State.DeleteUngeneratedProofs(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to initialize proofs cache %w", err)
+	}
+	return nil
+}
+
+// verifyStoredProofs deletes the stored generated proofs that are not valid
+// JSON, so they are generated again instead of failing later on aggregation or
+// verification.
+func (a *Aggregator) verifyStoredProofs(ctx context.Context) error {
+	if !a.cfg.VerifyStoredProofsOnStartup {
+		return nil
+	}
+
+	err := a.forEachStoredProof(ctx, func(proof *state.Proof) error {
+		if proof.GeneratingSince != nil || json.Valid([]byte(proof.Proof)) {
+			return nil
+		}
+		log.Warnf("Deleting invalid stored proof for batches %d-%d", proof.BatchNumber, proof.BatchNumberFinal)
+		err := a.State.DeleteGeneratedProofs(ctx, proof.BatchNumber, proof.BatchNumberFinal, nil)
+		if err != nil {
+			return fmt.Errorf("failed to delete invalid proof for batches %d-%d %w", proof.BatchNumber, proof.BatchNumberFinal, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to verify stored proofs, %w", err)
+	}
+	return nil
 }
 
 // Stop stops the Aggregator server.
@@ -160,8 +323,16 @@ func (a *Aggregator) Stop() {
 // Channel implements the bi-directional communication channel between the
 // Prover client and the Aggregator server.
 func (a *Aggregator) Channel(stream pb.AggregatorService_ChannelServer) error {
+	if !a.acquireProverSlot() {
+		log.Warnf("Rejecting prover stream, maximum number of connected provers (%d) reached", a.cfg.MaxConnectedProvers)
+		return status.Errorf(codes.ResourceExhausted, "maximum number of connected provers (%d) reached", a.cfg.MaxConnectedProvers)
+	}
 	metrics.ConnectedProver()
-	defer metrics.DisconnectedProver()
+	defer func() {
+		a.releaseProverSlot()
+		metrics.DisconnectedProver()
+	}()
+	connectedAt := a.clock.Now()
 
 	ctx := stream.Context()
 	var proverAddr net.Addr
@@ -169,25 +340,44 @@ func (a *Aggregator) Channel(stream pb.AggregatorService_ChannelServer) error {
 	if ok {
 		proverAddr = p.Addr
 	}
-	prover, err := prover.New(stream, proverAddr, a.cfg.ProofStatePollingInterval)
+	streamProver, err := prover.New(stream, proverAddr, a.cfg.ProofStatePollingInterval, a.cfg.MaxLoggedProofLength)
 	if err != nil {
 		return err
 	}
+	prover := a.withProverTransport(streamProver)
+	// the session tells apart the connections of the same prover, e.g. to
+	// follow a prover reconnecting
+	sessionID := uuid.NewString()
 
 	log := log.WithFields(
 		"prover", prover.Name(),
 		"proverId", prover.ID(),
 		"proverAddr", prover.Addr(),
+		"proverSession", sessionID,
 	)
 	log.Info("Establishing stream connection with prover")
 
 	// Check if prover supports the required Fork ID
-	if !prover.SupportsForkID(a.cfg.ForkId) {
+	if !streamProver.SupportsForkID(a.cfg.ForkId) {
 		err := errors.New("prover does not support required fork ID")
 		log.Warn(FirstToUpper(err.Error()))
 		return err
 	}
 
+	if warmup := connectedAt.Add(a.cfg.ProverWarmupDelay.Duration).Sub(a.clock.Now()); warmup > 0 {
+		log.Infof("Waiting %v for prover warm-up before sending proofs", warmup)
+		select {
+		case <-a.ctx.Done():
+			// server disconnected
+			return a.ctx.Err()
+		case <-ctx.Done():
+			// client disconnected
+			return ctx.Err()
+		case <-a.clock.After(warmup):
+		}
+		log.Info("Prover warm-up finished")
+	}
+
 	for {
 		select {
 		case <-a.ctx.Done():
@@ -198,6 +388,18 @@ func (a *Aggregator) Channel(stream pb.AggregatorService_ChannelServer) error {
 			return ctx.Err()
 
 		default:
+			if a.Paused() {
+				log.Debug("Proof generation is paused")
+				time.Sleep(a.cfg.RetryTime.Duration)
+				continue
+			}
+
+			if a.proverBlocklist.isBlocked(a.clock.Now, prover.ID()) {
+				log.Debug("Prover is blocklisted")
+				time.Sleep(a.cfg.RetryTime.Duration)
+				continue
+			}
+
 			isIdle, err := prover.IsIdle()
 			if err != nil {
 				log.Errorf("Failed to check if prover is idle: %v", err)
@@ -218,13 +420,18 @@ func (a *Aggregator) Channel(stream pb.AggregatorService_ChannelServer) error {
 			proofGenerated, err := a.tryAggregateProofs(ctx, prover)
 			if err != nil {
 				log.Errorf("Error trying to aggregate proofs: %v", err)
+				a.recordProverFailure(prover, err)
 			}
 			if !proofGenerated {
 				proofGenerated, err = a.tryGenerateBatchProof(ctx, prover)
 				if err != nil {
 					log.Errorf("Error trying to generate proof: %v", err)
+					a.recordProverFailure(prover, err)
 				}
 			}
+			if proofGenerated {
+				a.proverBlocklist.recordSuccess(prover.ID())
+			}
 			if !proofGenerated {
 				// if no proof was generated (aggregated or batch) wait some time before retry
 				time.Sleep(a.cfg.RetryTime.Duration)
@@ -233,6 +440,137 @@ func (a *Aggregator) Channel(stream pb.AggregatorService_ChannelServer) error {
 	}
 }
 
+// withProverTransport returns the prover to generate proofs with, getting the
+// generated proofs through the configured ProverTransport.
+func (a *Aggregator) withProverTransport(p *prover.Prover) proverInterface {
+	if a.cfg.ProverTransport == ProverTransportFile {
+		return prover.NewFileProver(p, a.cfg.ProverProofDir)
+	}
+	return p
+}
+
+// recordProverFailure counts a proof generation failure for the prover,
+// blocklisting it once it reaches the maximum consecutive failures allowed.
+// Only the errors returned by the prover itself are counted, the ones from the
+// state or L1 are not the prover's fault.
+func (a *Aggregator) recordProverFailure(prover proverInterface, err error) {
+	var pErr *proverError
+	if !errors.As(err, &pErr) {
+		return
+	}
+	if a.proverBlocklist.recordFailure(a.clock.Now, prover.ID()) {
+		log.Warnf("Prover %s (%s) failed %d times in a row, not sending it new proofs for %v",
+			prover.Name(), prover.ID(), a.cfg.ProverMaxConsecutiveFailures, a.cfg.ProverBlocklistDuration.Duration)
+		metrics.BlocklistedProver()
+	}
+}
+
+// recordAggregationFailure counts a failure aggregating the pair of proofs,
+// quarantining it once it reaches the maximum consecutive failures allowed so
+// the provers move on to other work.
+func (a *Aggregator) recordAggregationFailure(proof1, proof2 *state.Proof) {
+	if a.aggrQuarantine.recordFailure(proof1.BatchNumber, proof2.BatchNumberFinal) {
+		log.Errorf("Aggregation of proofs %d-%d and %d-%d failed %d times in a row, quarantining it until restart",
+			proof1.BatchNumber, proof1.BatchNumberFinal, proof2.BatchNumber, proof2.BatchNumberFinal, a.cfg.MaxAggregationRetries)
+		metrics.QuarantinedAggregation()
+	}
+}
+
+// tryLockWithTimeout acquires the StateDBMutex, giving up with
+// errStateLockTimeout if it is still held by someone else after the timeout.
+// A zero timeout waits indefinitely.
+func (a *Aggregator) tryLockWithTimeout(timeout time.Duration) error {
+	if timeout == 0 {
+		a.StateDBMutex.Lock()
+		return nil
+	}
+
+	deadline := a.clock.Now().Add(timeout)
+	for !a.StateDBMutex.TryLock() {
+		if a.clock.Now().After(deadline) {
+			log.Warnf("State lock still held after %v, giving up", timeout)
+			return errStateLockTimeout
+		}
+		<-a.clock.After(stateLockPollInterval)
+	}
+	return nil
+}
+
+// Pause stops dispatching new proof generations to the connected provers.
+// The proofs already being generated are finished.
+func (a *Aggregator) Pause() {
+	if atomic.CompareAndSwapInt32(&a.paused, 0, 1) {
+		log.Info("Proof generation paused")
+	}
+}
+
+// Resume restarts dispatching proof generations to the connected provers
+// after a Pause.
+func (a *Aggregator) Resume() {
+	if atomic.CompareAndSwapInt32(&a.paused, 1, 0) {
+		log.Info("Proof generation resumed")
+	}
+}
+
+// Paused returns true if proof generation is paused.
+func (a *Aggregator) Paused() bool {
+	return atomic.LoadInt32(&a.paused) == 1
+}
+
+// HandleResultByID runs again the handling of the result of the batch
+// verification monitored tx with the given ID, as done when the tx is
+// confirmed. Only confirmed txs can be handled.
+func (a *Aggregator) HandleResultByID(ctx context.Context, monitoredTxID string) error {
+	result, err := a.EthTxManager.Result(ctx, ethTxManagerOwner, monitoredTxID, nil)
+	if err != nil {
+		return fmt.Errorf("failed to get monitored tx %s result: %w", monitoredTxID, err)
+	}
+	if result.Status != ethtxmanager.MonitoredTxStatusConfirmed {
+		return fmt.Errorf("monitored tx %s is not confirmed, status: %s", monitoredTxID, result.Status)
+	}
+	a.handleMonitoredTxResult(result)
+	return nil
+}
+
+// acquireProverSlot reserves a slot for a new prover stream. It returns false
+// if the maximum number of connected provers has been reached.
+func (a *Aggregator) acquireProverSlot() bool {
+	n := atomic.AddInt32(&a.connectedProvers, 1)
+	if a.cfg.MaxConnectedProvers > 0 && int(n) > a.cfg.MaxConnectedProvers {
+		atomic.AddInt32(&a.connectedProvers, -1)
+		return false
+	}
+	return true
+}
+
+// releaseProverSlot frees the slot reserved by acquireProverSlot.
+func (a *Aggregator) releaseProverSlot() {
+	atomic.AddInt32(&a.connectedProvers, -1)
+}
+
+// warnNoProvers logs a warning every NoProverWarnInterval while no prover is
+// connected, so an aggregator without provers is not mistaken for a stuck
+// one.
+func (a *Aggregator) warnNoProvers() {
+	if a.cfg.NoProverWarnInterval.Duration == 0 {
+		return
+	}
+
+	noProversSince := a.clock.Now()
+	for {
+		select {
+		case <-a.ctx.Done():
+			return
+		case <-a.clock.After(a.cfg.NoProverWarnInterval.Duration):
+			if atomic.LoadInt32(&a.connectedProvers) > 0 {
+				noProversSince = a.clock.Now()
+				continue
+			}
+			log.Warnf("No prover connected for %v, no proofs are being generated", a.clock.Now().Sub(noProversSince).Round(time.Millisecond))
+		}
+	}
+}
+
 // This function waits to receive a final proof from a prover. Once it receives
 // the proof, it performs these steps in order:
 // - send the final proof to L1
@@ -267,6 +605,15 @@ func (a *Aggregator) sendFinalProof() {
 
 			log.Infof("Final proof inputs: NewLocalExitRoot [%#x], NewStateRoot [%#x]", inputs.NewLocalExitRoot, inputs.NewStateRoot)
 
+			public := msg.finalProof.GetPublic()
+			if !bytes.Equal(public.GetNewStateRoot(), inputs.NewStateRoot) || !bytes.Equal(public.GetNewLocalExitRoot(), inputs.NewLocalExitRoot) {
+				log.Errorf("Final proof roots do not match the batch: NewLocalExitRoot [%#x], NewStateRoot [%#x]. Discarding it to be generated again",
+					public.GetNewLocalExitRoot(), public.GetNewStateRoot())
+				metrics.FinalProofRootMismatch()
+				a.discardInvalidProof(ctx, proof)
+				continue
+			}
+
 			// add batch verification to be monitored
 			sender := common.HexToAddress(a.cfg.SenderAddress)
 			to, data, err := a.Ethman.BuildTrustedVerifyBatchesTxData(proof.BatchNumber-1, proof.BatchNumberFinal, &inputs)
@@ -275,6 +622,41 @@ func (a *Aggregator) sendFinalProof() {
 				a.handleFailureToAddVerifyBatchToBeMonitored(ctx, proof)
 				continue
 			}
+
+			if a.cfg.LocalVerifyBeforeSubmit {
+				var checkErr error
+				err = a.l1Breaker.call(a.clock.Now, func() error {
+					checkErr = a.Ethman.CheckTrustedVerifyBatches(ctx, sender, proof.BatchNumber-1, proof.BatchNumberFinal, &inputs)
+					if errors.Is(checkErr, ethman.ErrInvalidProof) {
+						// L1 did answer, it is the proof that is rejected
+						return nil
+					}
+					return checkErr
+				})
+				if err == nil {
+					err = checkErr
+				}
+				if errors.Is(err, ethman.ErrInvalidProof) {
+					log.Error("Final proof rejected by the verifier contract, discarding it to be generated again")
+					a.discardInvalidProof(ctx, proof)
+					continue
+				}
+				if err != nil {
+					log.Errorf("Error checking batch verification before adding it to eth tx manager: %v", err)
+					a.handleFailureToAddVerifyBatchToBeMonitored(ctx, proof)
+					continue
+				}
+			}
+
+			if a.cfg.ExportVerifyCalldataDir != "" {
+				path, err := exportVerifyCalldata(a.cfg.ExportVerifyCalldataDir, proof.BatchNumber, proof.BatchNumberFinal, to, data)
+				if err != nil {
+					log.Errorf("Failed to export batch verification calldata: %v", err)
+				} else {
+					log.Infof("Batch verification calldata exported to %s", path)
+				}
+			}
+
 			monitoredTxID := buildMonitoredTxID(proof.BatchNumber, proof.BatchNumberFinal)
 			err = a.EthTxManager.Add(ctx, ethTxManagerOwner, monitoredTxID, sender, to, nil, data, nil)
 			if err != nil {
@@ -305,10 +687,22 @@ func (a *Aggregator) handleFailureToAddVerifyBatchToBeMonitored(ctx context.Cont
 	a.endProofVerification()
 }
 
+// discardInvalidProof deletes a proof rejected by the verifier contract, so its
+// batches are proved again.
+func (a *Aggregator) discardInvalidProof(ctx context.Context, proof *state.Proof) {
+	log := log.WithFields("proofId", proof.ProofID, "batches", fmt.Sprintf("%d-%d", proof.BatchNumber, proof.BatchNumberFinal))
+	err := a.State.DeleteGeneratedProofs(ctx, proof.BatchNumber, proof.BatchNumberFinal, nil)
+	if err != nil {
+		log.Errorf("Failed to delete invalid proof: %v", err)
+	}
+	a.endProofVerification()
+}
+
 // buildFinalProof builds and return the final proof for an aggregated/batch proof.
 func (a *Aggregator) buildFinalProof(ctx context.Context, prover proverInterface, proof *state.Proof) (*pb.FinalProof, error) {
+	proverName := prover.Name()
 	log := log.WithFields(
-		"prover", prover.Name(),
+		"prover", proverName,
 		"proverId", prover.ID(),
 		"proverAddr", prover.Addr(),
 		"recursiveProofId", *proof.ProofID,
@@ -318,7 +712,8 @@ func (a *Aggregator) buildFinalProof(ctx context.Context, prover proverInterface
 
 	finalProofID, err := prover.FinalProof(proof.Proof, a.cfg.SenderAddress)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get final proof id: %w", err)
+		metrics.ProverProof(proverName, metrics.ProofResultLabelFailure)
+		return nil, fmt.Errorf("failed to get final proof id: %w", &proverError{err})
 	}
 	proof.ProofID = finalProofID
 
@@ -327,10 +722,12 @@ func (a *Aggregator) buildFinalProof(ctx context.Context, prover proverInterface
 
 	finalProof, err := prover.WaitFinalProof(ctx, *proof.ProofID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get final proof from prover: %w", err)
+		metrics.ProverProof(proverName, metrics.ProofResultLabelFailure)
+		return nil, fmt.Errorf("failed to get final proof from prover: %w", &proverError{err})
 	}
 
 	log.Info("Final proof generated")
+	metrics.ProverProof(proverName, metrics.ProofResultLabelSuccess)
 
 	// mock prover sanity check
 	if string(finalProof.Public.NewStateRoot) == mockedStateRoot && string(finalProof.Public.NewLocalExitRoot) == mockedLocalExitRoot {
@@ -362,6 +759,9 @@ func (a *Aggregator) tryBuildFinalProof(ctx context.Context, prover proverInterf
 		"proverId", proverID,
 		"proverAddr", prover.Addr(),
 	)
+	if proof != nil && proof.AttemptID != nil {
+		log = log.WithFields("attemptId", *proof.AttemptID)
+	}
 	log.Debug("tryBuildFinalProof start")
 
 	var err error
@@ -371,12 +771,24 @@ func (a *Aggregator) tryBuildFinalProof(ctx context.Context, prover proverInterf
 	}
 	log.Debug("Send final proof time reached")
 
-	for !a.isSynced(ctx, nil) {
-		log.Info("Waiting for synchronizer to sync...")
-		time.Sleep(a.cfg.RetryTime.Duration)
-		continue
+	if err = a.waitSynced(ctx, nil); err != nil {
+		return false, err
 	}
 
+	// the balance is checked before locking a proof to verify, so no proof is
+	// locked and released again while the balance is low
+	if err = a.checkMinSenderBalance(ctx); err != nil {
+		if !errors.Is(err, errInsufficientSenderBalance) {
+			return false, err
+		}
+		// expected until the sender is funded, not an error
+		if atomic.CompareAndSwapInt32(&a.lowSenderBalance, 0, 1) {
+			log.Warn(FirstToUpper(err.Error()))
+		}
+		return false, nil
+	}
+	atomic.StoreInt32(&a.lowSenderBalance, 0)
+
 	var lastVerifiedBatchNum uint64
 	lastVerifiedBatch, err := a.State.GetLastVerifiedBatch(ctx, nil)
 	if err != nil && !errors.Is(err, state.ErrNotFound) {
@@ -393,12 +805,15 @@ func (a *Aggregator) tryBuildFinalProof(ctx context.Context, prover proverInterf
 		proof, err = a.getAndLockProofReadyToVerify(ctx, prover, lastVerifiedBatchNum)
 		if errors.Is(err, state.ErrNotFound) {
 			// nothing to verify, swallow the error
-			log.Debug("No proof ready to verify")
+			a.updateFinalProofWait(lastVerifiedBatchNum + 1)
 			return false, nil
 		}
 		if err != nil {
 			return false, err
 		}
+		if proof.AttemptID != nil {
+			log = log.WithFields("attemptId", *proof.AttemptID)
+		}
 
 		defer func() {
 			if err != nil {
@@ -422,11 +837,26 @@ func (a *Aggregator) tryBuildFinalProof(ctx context.Context, prover proverInterf
 		}
 	}
 
+	err = a.checkFinalBatchSynced(ctx, proof.BatchNumberFinal)
+	if errors.Is(err, errFinalBatchNotSynced) {
+		// expected while the synchronizer catches up, not an error. err is
+		// kept set so the deferred unlock releases a locked proof
+		log.Info(FirstToUpper(err.Error()))
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
 	log = log.WithFields(
 		"proofId", *proof.ProofID,
 		"batches", fmt.Sprintf("%d-%d", proof.BatchNumber, proof.BatchNumberFinal),
 	)
 
+	a.finalProofWait.reset()
+	metrics.FinalProofWait(0)
+	a.updateFinalProofGap(proof.BatchNumberFinal, lastVerifiedBatchNum)
+
 	// at this point we have an eligible proof, build the final one using it
 	finalProof, err := a.buildFinalProof(ctx, prover, proof)
 	if err != nil {
@@ -452,20 +882,97 @@ func (a *Aggregator) tryBuildFinalProof(ctx context.Context, prover proverInterf
 	return true, nil
 }
 
+// checkFinalBatchSynced returns errFinalBatchNotSynced if the last batch of
+// the proof to verify has not been synced as a virtual batch yet, e.g. when
+// the synchronizer is behind after a reorg.
+func (a *Aggregator) checkFinalBatchSynced(ctx context.Context, batchNumberFinal uint64) error {
+	lastVirtualBatchNum, err := a.State.GetLastVirtualBatchNum(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to get last virtual batch number, %w", err)
+	}
+	if lastVirtualBatchNum < batchNumberFinal {
+		return fmt.Errorf("%w, batch %d, last virtual batch %d", errFinalBatchNotSynced, batchNumberFinal, lastVirtualBatchNum)
+	}
+	return nil
+}
+
+// checkMinSenderBalance returns errInsufficientSenderBalance if the sender
+// has less than MinSenderBalance on L1 to pay for the batch verification txs.
+func (a *Aggregator) checkMinSenderBalance(ctx context.Context) error {
+	if a.cfg.MinSenderBalance.Int == nil {
+		return nil
+	}
+
+	sender := common.HexToAddress(a.cfg.SenderAddress)
+	var balance *big.Int
+	err := a.l1Breaker.call(a.clock.Now, func() error {
+		var err error
+		balance, err = a.Ethman.GetBalance(ctx, sender)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get the balance of sender %s, %w", sender, err)
+	}
+	if balance.Cmp(a.cfg.MinSenderBalance.Int) < 0 {
+		return fmt.Errorf("%w, sender %s: %s wei, minimum %s wei", errInsufficientSenderBalance, sender, balance, a.cfg.MinSenderBalance.Int)
+	}
+	return nil
+}
+
+// updateFinalProofWait updates the metric for the time the batch has been
+// waiting for a proof ready to verify, warning once it exceeds
+// FinalProofWaitWarning.
+func (a *Aggregator) updateFinalProofWait(batchNum uint64) {
+	waited := a.finalProofWait.elapsed(batchNum, a.clock.Now())
+	metrics.FinalProofWait(waited)
+	if a.cfg.FinalProofWaitWarning.Duration > 0 && waited >= a.cfg.FinalProofWaitWarning.Duration {
+		log.Warnf("No proof ready to verify batch %d for %v", batchNum, waited)
+		return
+	}
+	log.Debug("No proof ready to verify")
+}
+
+// updateFinalProofGap updates the metric for the number of batches between
+// the final proof being built and the last verified batch, warning if it
+// exceeds FinalProofGapWarningThreshold.
+func (a *Aggregator) updateFinalProofGap(batchNumberFinal, lastVerifiedBatchNum uint64) {
+	var gap uint64
+	if batchNumberFinal > lastVerifiedBatchNum {
+		gap = batchNumberFinal - lastVerifiedBatchNum
+	}
+	metrics.FinalProofGap(gap)
+	if a.cfg.FinalProofGapWarningThreshold > 0 && gap > a.cfg.FinalProofGapWarningThreshold {
+		log.Warnf("Building final proof up to batch %d, %d batches ahead of last verified batch %d", batchNumberFinal, gap, lastVerifiedBatchNum)
+	}
+}
+
 func (a *Aggregator) validateEligibleFinalProof(ctx context.Context, proof *state.Proof, lastVerifiedBatchNum uint64) (bool, error) {
 	batchNumberToVerify := lastVerifiedBatchNum + 1
 
+	if a.exceedsMaxBatchesPerFinalProof(proof) {
+		return false, nil
+	}
+
 	if proof.BatchNumber != batchNumberToVerify {
-		if proof.BatchNumber < batchNumberToVerify && proof.BatchNumberFinal >= batchNumberToVerify {
+		if proof.BatchNumber < batchNumberToVerify && proof.BatchNumberFinal >= batchNumberToVerify && !a.cfg.DiscardOverlappingFinalProofs {
 			// We have a proof that contains some batches below the last batch verified, anyway can be eligible as final proof
 			log.Warnf("Proof %d-%d contains some batches lower than last batch verified %d. Check anyway if it is eligible", proof.BatchNumber, proof.BatchNumberFinal, lastVerifiedBatchNum)
-		} else if proof.BatchNumberFinal < batchNumberToVerify {
-			// We have a proof that contains batches below that the last batch verified, we need to delete this proof
-			log.Warnf("Proof %d-%d lower than next batch to verify %d. Deleting it", proof.BatchNumber, proof.BatchNumberFinal, batchNumberToVerify)
+		} else if proof.BatchNumber < batchNumberToVerify {
+			// We have a proof that starts below the next batch to verify, it is
+			// stale and must not be sent to L1, we need to delete this proof
+			// once the deletion grace window has elapsed
+			if grace := a.cfg.ProofDeletionGrace.Duration; grace > 0 {
+				if stale := a.staleProofs.elapsed(proof.BatchNumber, proof.BatchNumberFinal, a.clock.Now()); stale < grace {
+					log.Debugf("Stale proof %d-%d starts below next batch to verify %d. Keeping it for %v", proof.BatchNumber, proof.BatchNumberFinal, batchNumberToVerify, grace-stale)
+					return false, nil
+				}
+			}
+			log.Warnf("Stale proof %d-%d starts below next batch to verify %d. Deleting it", proof.BatchNumber, proof.BatchNumberFinal, batchNumberToVerify)
 			err := a.State.DeleteGeneratedProofs(ctx, proof.BatchNumber, proof.BatchNumberFinal, nil)
 			if err != nil {
 				return false, fmt.Errorf("failed to delete discarded proof, err: %w", err)
 			}
+			a.staleProofs.remove(proof.BatchNumber, proof.BatchNumberFinal)
 			return false, nil
 		} else {
 			log.Debugf("Proof batch number %d is not the following to last verfied batch number %d", proof.BatchNumber, lastVerifiedBatchNum)
@@ -484,8 +991,25 @@ func (a *Aggregator) validateEligibleFinalProof(ctx context.Context, proof *stat
 	return true, nil
 }
 
+// exceedsMaxBatchesPerFinalProof returns true if the proof spans more batches
+// than allowed to build a final proof.
+func (a *Aggregator) exceedsMaxBatchesPerFinalProof(proof *state.Proof) bool {
+	if a.cfg.MaxBatchesPerFinalProof == 0 {
+		return false
+	}
+	numBatches := proof.BatchNumberFinal - proof.BatchNumber + 1
+	if numBatches > a.cfg.MaxBatchesPerFinalProof {
+		log.Warnf("Proof %d-%d spans %d batches, more than the maximum allowed for a final proof (%d)",
+			proof.BatchNumber, proof.BatchNumberFinal, numBatches, a.cfg.MaxBatchesPerFinalProof)
+		return true
+	}
+	return false
+}
+
 func (a *Aggregator) getAndLockProofReadyToVerify(ctx context.Context, prover proverInterface, lastVerifiedBatchNum uint64) (*state.Proof, error) {
-	a.StateDBMutex.Lock()
+	if err := a.tryLockWithTimeout(a.cfg.StateLockTimeout.Duration); err != nil {
+		return nil, err
+	}
 	defer a.StateDBMutex.Unlock()
 
 	// Get proof ready to be verified
@@ -494,7 +1018,11 @@ func (a *Aggregator) getAndLockProofReadyToVerify(ctx context.Context, prover pr
 		return nil, err
 	}
 
-	now := time.Now().Round(time.Microsecond)
+	if a.exceedsMaxBatchesPerFinalProof(proofToVerify) {
+		return nil, state.ErrNotFound
+	}
+
+	now := a.clock.Now().Round(time.Microsecond)
 	proofToVerify.GeneratingSince = &now
 
 	err = a.State.UpdateGeneratedProof(ctx, proofToVerify, nil)
@@ -505,7 +1033,27 @@ func (a *Aggregator) getAndLockProofReadyToVerify(ctx context.Context, prover pr
 	return proofToVerify, nil
 }
 
+// unlockProofsToAggregate releases the proofs to aggregate from the generating
+// state, retrying up to UnlockProofsMaxRetries times with an exponential
+// backoff so a transient state error doesn't leave them locked.
 func (a *Aggregator) unlockProofsToAggregate(ctx context.Context, proof1 *state.Proof, proof2 *state.Proof) error {
+	wait := unlockProofsRetryInterval
+	for retry := 0; ; retry++ {
+		err := a.tryUnlockProofsToAggregate(ctx, proof1, proof2)
+		if err == nil || retry >= a.cfg.UnlockProofsMaxRetries {
+			return err
+		}
+		log.Warnf("Failed to release proof aggregation state, retrying in %v: %v", wait, err)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-a.clock.After(wait):
+		}
+		wait *= 2
+	}
+}
+
+func (a *Aggregator) tryUnlockProofsToAggregate(ctx context.Context, proof1 *state.Proof, proof2 *state.Proof) error {
 	// Release proofs from generating state in a single transaction
 	dbTx, err := a.State.BeginStateTransaction(ctx)
 	if err != nil {
@@ -537,19 +1085,42 @@ func (a *Aggregator) unlockProofsToAggregate(ctx context.Context, proof1 *state.
 	return nil
 }
 
-func (a *Aggregator) getAndLockProofsToAggregate(ctx context.Context, prover proverInterface) (*state.Proof, *state.Proof, error) {
+func (a *Aggregator) getAndLockProofsToAggregate(ctx context.Context, prover proverInterface, attemptID string) (*state.Proof, *state.Proof, error) {
+	proverID := prover.ID()
+
 	log := log.WithFields(
 		"prover", prover.Name(),
-		"proverId", prover.ID(),
+		"proverId", proverID,
 		"proverAddr", prover.Addr(),
+		"attemptId", attemptID,
 	)
 
-	a.StateDBMutex.Lock()
+	if err := a.tryLockWithTimeout(a.cfg.StateLockTimeout.Duration); err != nil {
+		return nil, nil, err
+	}
 	defer a.StateDBMutex.Unlock()
 
-	proof1, proof2, err := a.State.GetProofsToAggregate(ctx, nil)
-	if err != nil {
-		return nil, nil, err
+	// the prover locks are counted while holding the StateDBMutex
+	if a.proverLocks.full(proverID) {
+		log.Debugf("Prover reached the maximum of %d locked proofs", a.cfg.MaxLockedBatchesPerProver)
+		return nil, nil, state.ErrNotFound
+	}
+
+	// skip the quarantined pairs, so they don't hold back the aggregation of
+	// the ones after them
+	var fromBatchNum uint64
+	var proof1, proof2 *state.Proof
+	for {
+		var err error
+		proof1, proof2, err = a.State.GetProofsToAggregate(ctx, fromBatchNum, a.cfg.MaxBatchesPerFinalProof, nil)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !a.aggrQuarantine.isQuarantined(proof1.BatchNumber, proof2.BatchNumberFinal) {
+			break
+		}
+		log.Debugf("Proofs for batches %d-%d are quarantined", proof1.BatchNumber, proof2.BatchNumberFinal)
+		fromBatchNum = proof1.BatchNumber + 1
 	}
 
 	// Set proofs in generating state in a single transaction
@@ -559,7 +1130,7 @@ func (a *Aggregator) getAndLockProofsToAggregate(ctx context.Context, prover pro
 		return nil, nil, err
 	}
 
-	now := time.Now().Round(time.Microsecond)
+	now := a.clock.Now().Round(time.Microsecond)
 	proof1.GeneratingSince = &now
 	err = a.State.UpdateGeneratedProof(ctx, proof1, dbTx)
 	if err == nil {
@@ -580,6 +1151,7 @@ func (a *Aggregator) getAndLockProofsToAggregate(ctx context.Context, prover pro
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to set proof aggregation state %w", err)
 	}
+	a.proverLocks.add(proverID)
 
 	return proof1, proof2, nil
 }
@@ -587,15 +1159,17 @@ func (a *Aggregator) getAndLockProofsToAggregate(ctx context.Context, prover pro
 func (a *Aggregator) tryAggregateProofs(ctx context.Context, prover proverInterface) (bool, error) {
 	proverName := prover.Name()
 	proverID := prover.ID()
+	attemptID := uuid.NewString()
 
 	log := log.WithFields(
 		"prover", proverName,
 		"proverId", proverID,
 		"proverAddr", prover.Addr(),
+		"attemptId", attemptID,
 	)
 	log.Debug("tryAggregateProofs start")
 
-	proof1, proof2, err0 := a.getAndLockProofsToAggregate(ctx, prover)
+	proof1, proof2, err0 := a.getAndLockProofsToAggregate(ctx, prover, attemptID)
 	if errors.Is(err0, state.ErrNotFound) {
 		// nothing to aggregate, swallow the error
 		log.Debug("Nothing to aggregate")
@@ -604,6 +1178,7 @@ func (a *Aggregator) tryAggregateProofs(ctx context.Context, prover proverInterf
 	if err0 != nil {
 		return false, err0
 	}
+	defer a.proverLocks.release(proverID)
 
 	var (
 		aggrProofID *string
@@ -616,6 +1191,9 @@ func (a *Aggregator) tryAggregateProofs(ctx context.Context, prover proverInterf
 			if err2 != nil {
 				log.Errorf("Failed to release aggregated proofs, err: %v", err2)
 			}
+			a.recordAggregationFailure(proof1, proof2)
+		} else {
+			a.aggrQuarantine.recordSuccess(proof1.BatchNumber, proof2.BatchNumberFinal)
 		}
 		log.Debug("tryAggregateProofs end")
 	}()
@@ -641,12 +1219,14 @@ func (a *Aggregator) tryAggregateProofs(ctx context.Context, prover proverInterf
 		BatchNumberFinal: proof2.BatchNumberFinal,
 		Prover:           &proverName,
 		ProverID:         &proverID,
+		AttemptID:        &attemptID,
 		InputProver:      string(b),
 	}
 
 	aggrProofID, err = prover.AggregatedProof(proof1.Proof, proof2.Proof)
 	if err != nil {
-		err = fmt.Errorf("failed to get aggregated proof id, %w", err)
+		metrics.ProverProof(proverName, metrics.ProofResultLabelFailure)
+		err = fmt.Errorf("failed to get aggregated proof id, %w", &proverError{err})
 		log.Error(FirstToUpper(err.Error()))
 		return false, err
 	}
@@ -658,12 +1238,28 @@ func (a *Aggregator) tryAggregateProofs(ctx context.Context, prover proverInterf
 
 	recursiveProof, err := prover.WaitRecursiveProof(ctx, *proof.ProofID)
 	if err != nil {
-		err = fmt.Errorf("failed to get aggregated proof from prover, %w", err)
+		metrics.ProverProof(proverName, metrics.ProofResultLabelFailure)
+		err = fmt.Errorf("failed to get aggregated proof from prover, %w", &proverError{err})
+		log.Error(FirstToUpper(err.Error()))
+		return false, err
+	}
+
+	if recursiveProof == "" {
+		metrics.ProverProof(proverName, metrics.ProofResultLabelFailure)
+		err = fmt.Errorf("failed to get aggregated proof from prover, %w", &proverError{errEmptyProof})
+		log.Error(FirstToUpper(err.Error()))
+		return false, err
+	}
+
+	if !json.Valid([]byte(recursiveProof)) {
+		metrics.ProverProof(proverName, metrics.ProofResultLabelFailure)
+		err = fmt.Errorf("failed to get aggregated proof from prover, %w", &proverError{errInvalidProof})
 		log.Error(FirstToUpper(err.Error()))
 		return false, err
 	}
 
 	log.Info("Aggregated proof generated")
+	metrics.ProverProof(proverName, metrics.ProofResultLabelSuccess)
 
 	proof.Proof = recursiveProof
 
@@ -688,7 +1284,7 @@ func (a *Aggregator) tryAggregateProofs(ctx context.Context, prover proverInterf
 		return false, err
 	}
 
-	now := time.Now().Round(time.Microsecond)
+	now := a.clock.Now().Round(time.Microsecond)
 	proof.GeneratingSince = &now
 
 	err = a.State.AddGeneratedProof(ctx, proof, dbTx)
@@ -738,7 +1334,29 @@ func (a *Aggregator) tryAggregateProofs(ctx context.Context, prover proverInterf
 	return true, nil
 }
 
-func (a *Aggregator) getAndLockBatchToProve(ctx context.Context, prover proverInterface) (*state.Batch, *state.Proof, error) {
+// checkSequencedAccInputHash checks the accumulated input hash of the batch
+// against the one stored on L1. L1 only stores it for the last batch of each
+// sequence, any other batch can't be checked.
+func (a *Aggregator) checkSequencedAccInputHash(ctx context.Context, batch *state.Batch) error {
+	var accInputHash common.Hash
+	err := a.l1Breaker.call(a.clock.Now, func() error {
+		var err error
+		accInputHash, err = a.Ethman.GetSequencedBatchAccInputHash(ctx, batch.BatchNumber)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get accumulated input hash of batch %d from L1, %w", batch.BatchNumber, err)
+	}
+	if accInputHash == (common.Hash{}) {
+		return nil
+	}
+	if accInputHash != batch.AccInputHash {
+		return fmt.Errorf("%w, batch %d: state %s, L1 %s", errAccInputHashMismatch, batch.BatchNumber, batch.AccInputHash, accInputHash)
+	}
+	return nil
+}
+
+func (a *Aggregator) getAndLockBatchToProve(ctx context.Context, prover proverInterface, attemptID string) (*state.Batch, *state.Proof, error) {
 	proverID := prover.ID()
 	proverName := prover.Name()
 
@@ -746,18 +1364,27 @@ func (a *Aggregator) getAndLockBatchToProve(ctx context.Context, prover proverIn
 		"prover", proverName,
 		"proverId", proverID,
 		"proverAddr", prover.Addr(),
+		"attemptId", attemptID,
 	)
 
-	a.StateDBMutex.Lock()
+	if err := a.tryLockWithTimeout(a.cfg.StateLockTimeout.Duration); err != nil {
+		return nil, nil, err
+	}
 	defer a.StateDBMutex.Unlock()
 
+	// the prover locks are counted while holding the StateDBMutex
+	if a.proverLocks.full(proverID) {
+		log.Debugf("Prover reached the maximum of %d locked proofs", a.cfg.MaxLockedBatchesPerProver)
+		return nil, nil, state.ErrNotFound
+	}
+
 	lastVerifiedBatch, err := a.State.GetLastVerifiedBatch(ctx, nil)
 	if err != nil {
 		return nil, nil, err
 	}
 
 	// Get virtual batch pending to generate proof
-	batchToVerify, err := a.State.GetVirtualBatchToProve(ctx, lastVerifiedBatch.BatchNumber, nil)
+	batchToVerify, err := a.getVirtualBatchToProve(ctx, lastVerifiedBatch.BatchNumber)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -765,26 +1392,33 @@ func (a *Aggregator) getAndLockBatchToProve(ctx context.Context, prover proverIn
 	log.Infof("Found virtual batch %d pending to generate proof", batchToVerify.BatchNumber)
 	log = log.WithFields("batch", batchToVerify.BatchNumber)
 
-	log.Info("Checking profitability to aggregate batch")
+	if batchToVerify.ForcedBatchNum != nil {
+		// forced batches must be proved regardless of the reward, otherwise
+		// the chain would stall on them
+		log.Infof("Forced batch %d, skipping profitability check", *batchToVerify.ForcedBatchNum)
+	} else {
+		log.Info("Checking profitability to aggregate batch")
 
-	// pass matic collateral as zero here, bcs in smart contract fee for aggregator is not defined yet
-	isProfitable, err := a.ProfitabilityChecker.IsProfitable(ctx, big.NewInt(0))
-	if err != nil {
-		log.Errorf("Failed to check aggregator profitability, err: %v", err)
-		return nil, nil, err
-	}
+		// pass matic collateral as zero here, bcs in smart contract fee for aggregator is not defined yet
+		isProfitable, err := a.ProfitabilityChecker.IsProfitable(ctx, big.NewInt(0))
+		if err != nil {
+			log.Errorf("Failed to check aggregator profitability, err: %v", err)
+			return nil, nil, err
+		}
 
-	if !isProfitable {
-		log.Infof("Batch is not profitable, matic collateral %d", big.NewInt(0))
-		return nil, nil, err
+		if !isProfitable {
+			log.Infof("Batch is not profitable, matic collateral %d", big.NewInt(0))
+			return nil, nil, state.ErrNotFound
+		}
 	}
 
-	now := time.Now().Round(time.Microsecond)
+	now := a.clock.Now().Round(time.Microsecond)
 	proof := &state.Proof{
 		BatchNumber:      batchToVerify.BatchNumber,
 		BatchNumberFinal: batchToVerify.BatchNumber,
 		Prover:           &proverName,
 		ProverID:         &proverID,
+		AttemptID:        &attemptID,
 		GeneratingSince:  &now,
 	}
 
@@ -794,27 +1428,111 @@ func (a *Aggregator) getAndLockBatchToProve(ctx context.Context, prover proverIn
 		log.Errorf("Failed to add batch proof, err: %v", err)
 		return nil, nil, err
 	}
+	a.proverLocks.add(proverID)
 
 	return batchToVerify, proof, nil
 }
 
+// getVirtualBatchToProve returns the next batch to prove, looking first into
+// the configured batch proof priorities and falling back to the lowest batch
+// not proved yet. The batches in the excluded ranges are skipped, and the
+// ones more than MaxProveAheadBatches after the last verified batch are not
+// proved yet.
+func (a *Aggregator) getVirtualBatchToProve(ctx context.Context, lastVerifiedBatchNum uint64) (*state.Batch, error) {
+	for _, p := range a.cfg.BatchProofPriorities {
+		batch, err := a.State.GetVirtualBatchToProveInRange(ctx, lastVerifiedBatchNum, p.FromBatch, p.ToBatch, nil)
+		if err == nil {
+			batch, err = a.skipExcludedBatches(ctx, lastVerifiedBatchNum, batch, p.ToBatch)
+		}
+		if err == nil && a.isTooFarAhead(batch.BatchNumber, lastVerifiedBatchNum) {
+			err = state.ErrNotFound
+		}
+		if errors.Is(err, state.ErrNotFound) {
+			continue
+		}
+		return batch, err
+	}
+	batch, err := a.State.GetVirtualBatchToProve(ctx, lastVerifiedBatchNum, nil)
+	if err != nil {
+		return nil, err
+	}
+	batch, err = a.skipExcludedBatches(ctx, lastVerifiedBatchNum, batch, math.MaxInt64)
+	if err != nil {
+		return nil, err
+	}
+	if a.isTooFarAhead(batch.BatchNumber, lastVerifiedBatchNum) {
+		log.Debugf("Next batch to prove %d is more than %d batches ahead of the last verified batch %d",
+			batch.BatchNumber, a.cfg.MaxProveAheadBatches, lastVerifiedBatchNum)
+		return nil, state.ErrNotFound
+	}
+	return batch, nil
+}
+
+// isTooFarAhead returns true if the batch is more than MaxProveAheadBatches
+// after the last verified batch.
+func (a *Aggregator) isTooFarAhead(batchNumber, lastVerifiedBatchNum uint64) bool {
+	return a.cfg.MaxProveAheadBatches > 0 && batchNumber > lastVerifiedBatchNum+a.cfg.MaxProveAheadBatches
+}
+
+// skipExcludedBatches returns the given batch if it is not in an excluded
+// range, otherwise the next batch to prove after the excluded range, up to
+// toBatch.
+func (a *Aggregator) skipExcludedBatches(ctx context.Context, lastVerifiedBatchNum uint64, batch *state.Batch, toBatch uint64) (*state.Batch, error) {
+	for {
+		excluded, ok := a.excludedBatchRange(batch.BatchNumber)
+		if !ok {
+			return batch, nil
+		}
+		log.Infof("Skipping batches %d-%d, excluded from proving", excluded.FromBatch, excluded.ToBatch)
+		if excluded.ToBatch >= toBatch {
+			return nil, state.ErrNotFound
+		}
+		var err error
+		batch, err = a.State.GetVirtualBatchToProveInRange(ctx, lastVerifiedBatchNum, excluded.ToBatch+1, toBatch, nil)
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// excludedBatchRange returns the excluded range the batch is in, if any.
+func (a *Aggregator) excludedBatchRange(batchNumber uint64) (BatchRange, bool) {
+	for _, r := range a.cfg.ExcludedBatchRanges {
+		if batchNumber >= r.FromBatch && batchNumber <= r.ToBatch {
+			return r, true
+		}
+	}
+	return BatchRange{}, false
+}
+
 func (a *Aggregator) tryGenerateBatchProof(ctx context.Context, prover proverInterface) (bool, error) {
+	proverName := prover.Name()
+	proverID := prover.ID()
+	attemptID := uuid.NewString()
+
 	log := log.WithFields(
-		"prover", prover.Name(),
-		"proverId", prover.ID(),
+		"prover", proverName,
+		"proverId", proverID,
 		"proverAddr", prover.Addr(),
+		"attemptId", attemptID,
 	)
 	log.Debug("tryGenerateBatchProof start")
 
-	batchToProve, proof, err0 := a.getAndLockBatchToProve(ctx, prover)
+	batchToProve, proof, err0 := a.getAndLockBatchToProve(ctx, prover, attemptID)
 	if errors.Is(err0, state.ErrNotFound) {
 		// nothing to proof, swallow the error
 		log.Debug("Nothing to generate proof")
 		return false, nil
 	}
+	if errors.Is(err0, state.ErrProofAlreadyClaimed) {
+		// another prover claimed the batch in the meantime, swallow the error
+		log.Debug("Batch already claimed by another prover")
+		return false, nil
+	}
 	if err0 != nil {
 		return false, err0
 	}
+	defer a.proverLocks.release(proverID)
 
 	log = log.WithFields("batch", batchToProve.BatchNumber)
 
@@ -835,6 +1553,15 @@ func (a *Aggregator) tryGenerateBatchProof(ctx context.Context, prover proverInt
 
 	log.Info("Generating proof from batch")
 
+	if a.cfg.ValidateSequencedAccInputHash {
+		err = a.checkSequencedAccInputHash(ctx, batchToProve)
+		if err != nil {
+			err = fmt.Errorf("failed to validate sequenced batch, %w", err)
+			log.Error(FirstToUpper(err.Error()))
+			return false, err
+		}
+	}
+
 	log.Infof("Sending zki + batch to the prover, batchNumber [%d]", batchToProve.BatchNumber)
 	inputProver, err := a.buildInputProver(ctx, batchToProve)
 	if err != nil {
@@ -843,7 +1570,7 @@ func (a *Aggregator) tryGenerateBatchProof(ctx context.Context, prover proverInt
 		return false, err
 	}
 
-	b, err := json.Marshal(inputProver)
+	b, err := encodeInputProver(inputProver, a.cfg.InputProverStorageFormat)
 	if err != nil {
 		err = fmt.Errorf("failed to serialize input prover, %w", err)
 		log.Error(FirstToUpper(err.Error()))
@@ -851,13 +1578,26 @@ func (a *Aggregator) tryGenerateBatchProof(ctx context.Context, prover proverInt
 	}
 
 	proof.InputProver = string(b)
+	if a.cfg.MaxInputProverBytes > 0 && uint64(len(b)) > a.cfg.MaxInputProverBytes {
+		log.Warnf("Input prover for batch %d is %d bytes, bigger than the maximum of %d bytes, storing a reference instead",
+			batchToProve.BatchNumber, len(b), a.cfg.MaxInputProverBytes)
+		metrics.OversizedInputProver()
+		ref, err := json.Marshal(omittedInputProver{BatchNumber: batchToProve.BatchNumber, Size: len(b)})
+		if err != nil {
+			err = fmt.Errorf("failed to serialize input prover reference, %w", err)
+			log.Error(FirstToUpper(err.Error()))
+			return false, err
+		}
+		proof.InputProver = string(ref)
+	}
 
 	log.Infof("Sending a batch to the prover. OldStateRoot [%#x], OldBatchNum [%d]",
 		inputProver.PublicInputs.OldStateRoot, inputProver.PublicInputs.OldBatchNum)
 
 	genProofID, err = prover.BatchProof(inputProver)
 	if err != nil {
-		err = fmt.Errorf("failed to get batch proof id, %w", err)
+		metrics.ProverProof(proverName, metrics.ProofResultLabelFailure)
+		err = fmt.Errorf("failed to get batch proof id, %w", &proverError{err})
 		log.Error(FirstToUpper(err.Error()))
 		return false, err
 	}
@@ -869,12 +1609,28 @@ func (a *Aggregator) tryGenerateBatchProof(ctx context.Context, prover proverInt
 
 	resGetProof, err := prover.WaitRecursiveProof(ctx, *proof.ProofID)
 	if err != nil {
-		err = fmt.Errorf("failed to get proof from prover, %w", err)
+		metrics.ProverProof(proverName, metrics.ProofResultLabelFailure)
+		err = fmt.Errorf("failed to get proof from prover, %w", &proverError{err})
+		log.Error(FirstToUpper(err.Error()))
+		return false, err
+	}
+
+	if resGetProof == "" {
+		metrics.ProverProof(proverName, metrics.ProofResultLabelFailure)
+		err = fmt.Errorf("failed to get proof from prover, %w", &proverError{errEmptyProof})
+		log.Error(FirstToUpper(err.Error()))
+		return false, err
+	}
+
+	if !json.Valid([]byte(resGetProof)) {
+		metrics.ProverProof(proverName, metrics.ProofResultLabelFailure)
+		err = fmt.Errorf("failed to get proof from prover, %w", &proverError{errInvalidProof})
 		log.Error(FirstToUpper(err.Error()))
 		return false, err
 	}
 
 	log.Info("Batch proof generated")
+	metrics.ProverProof(proverName, metrics.ProofResultLabelSuccess)
 
 	proof.Proof = resGetProof
 
@@ -904,12 +1660,40 @@ func (a *Aggregator) tryGenerateBatchProof(ctx context.Context, prover proverInt
 	return true, nil
 }
 
+// ReplayInputProver sends again to the prover a batch proof input previously
+// stored in state.Proof.InputProver, in any of the storage formats, and waits
+// for the resulting recursive proof. It is meant to reproduce failing proofs
+// while debugging, so the state is not modified.
+func (a *Aggregator) ReplayInputProver(ctx context.Context, prover proverInterface, storedInputProver string) (string, error) {
+	inputProver, err := decodeInputProver(storedInputProver)
+	if err != nil {
+		return "", fmt.Errorf("failed to deserialize input prover, %w", err)
+	}
+	if inputProver.PublicInputs == nil {
+		return "", errors.New("input prover has no public inputs, it may have been omitted for exceeding the maximum size")
+	}
+
+	proofID, err := prover.BatchProof(inputProver)
+	if err != nil {
+		return "", fmt.Errorf("failed to get batch proof id, %w", err)
+	}
+
+	log.WithFields("prover", prover.Name(), "proverId", prover.ID(), "proofId", *proofID).
+		Info("Replaying input prover")
+
+	recursiveProof, err := prover.WaitRecursiveProof(ctx, *proofID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get proof from prover, %w", err)
+	}
+	return recursiveProof, nil
+}
+
 // canVerifyProof returns true if we have reached the timeout to verify a proof
 // and no other prover is verifying a proof (verifyingProof = false).
 func (a *Aggregator) canVerifyProof() bool {
 	a.TimeSendFinalProofMutex.RLock()
 	defer a.TimeSendFinalProofMutex.RUnlock()
-	return a.TimeSendFinalProof.Before(time.Now()) && !a.verifyingProof
+	return a.TimeSendFinalProof.Before(a.clock.Now()) && !a.verifyingProof
 }
 
 // startProofVerification sets to true the verifyingProof variable to indicate that there is a proof verification in progress
@@ -930,7 +1714,7 @@ func (a *Aggregator) endProofVerification() {
 func (a *Aggregator) resetVerifyProofTime() {
 	a.TimeSendFinalProofMutex.Lock()
 	defer a.TimeSendFinalProofMutex.Unlock()
-	a.TimeSendFinalProof = time.Now().Add(a.cfg.VerifyProofInterval.Duration)
+	a.TimeSendFinalProof = a.clock.Now().Add(a.cfg.VerifyProofInterval.Duration)
 }
 
 // isSynced checks if the state is synchronized with L1. If a batch number is
@@ -956,7 +1740,12 @@ func (a *Aggregator) isSynced(ctx context.Context, batchNum *uint64) bool {
 	}
 
 	// latest verified batch in L1
-	lastVerifiedEthBatchNum, err := a.Ethman.GetLatestVerifiedBatchNum()
+	var lastVerifiedEthBatchNum uint64
+	err = a.l1Breaker.call(a.clock.Now, func() error {
+		var err error
+		lastVerifiedEthBatchNum, err = a.Ethman.GetLatestVerifiedBatchNum()
+		return err
+	})
 	if err != nil {
 		log.Warnf("Failed to get last eth batch, err: %v", err)
 		return false
@@ -969,14 +1758,69 @@ func (a *Aggregator) isSynced(ctx context.Context, batchNum *uint64) bool {
 		return false
 	}
 
+	// check if L1 verifications known by L2 have been reverted by an L1 reorg.
+	// Once the synchronizer handles the reorg, the proofs of the reverted
+	// batches are generated and sent again.
+	if lastVerifiedBatch.BatchNumber > lastVerifiedEthBatchNum {
+		log.Warnf("State is ahead of L1 verification, lastVerifiedBatchNum: %d, lastVerifiedEthBatchNum: %d, L1 verifications may have been reorged",
+			lastVerifiedBatch.BatchNumber, lastVerifiedEthBatchNum)
+		metrics.StateAheadOfL1()
+		a.batchCache.purge()
+		if max := a.cfg.MaxStateAheadOfL1.Duration; max > 0 {
+			if ahead := a.stateAheadOfL1.elapsed(a.clock.Now()); ahead > max {
+				// the synchronizer is not handling the L1 reorg, waiting
+				// longer won't get the state synced
+				a.reportFatal(fmt.Errorf("state ahead of L1 verification for more than %v, lastVerifiedBatchNum: %d, lastVerifiedEthBatchNum: %d",
+					max, lastVerifiedBatch.BatchNumber, lastVerifiedEthBatchNum))
+			}
+		}
+		return false
+	}
+	a.stateAheadOfL1.reset()
+
 	return true
 }
 
+// waitSynced waits until the state is synchronized with L1, see isSynced. It
+// returns an error if the aggregator is stopped or the context is done while
+// waiting.
+func (a *Aggregator) waitSynced(ctx context.Context, batchNum *uint64) error {
+	for !a.isSynced(ctx, batchNum) {
+		log.Info("Waiting for synchronizer to sync...")
+		select {
+		case <-a.ctx.Done():
+			return a.ctx.Err()
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-a.clock.After(a.cfg.RetryTime.Duration):
+		}
+	}
+	return nil
+}
+
 func (a *Aggregator) buildInputProver(ctx context.Context, batchToVerify *state.Batch) (*pb.InputProver, error) {
-	previousBatch, err := a.State.GetBatchByNumber(ctx, batchToVerify.BatchNumber-1, nil)
-	if err != nil && err != state.ErrStateNotSynchronized {
-		return nil, fmt.Errorf("failed to get previous batch, err: %v", err)
+	if batchToVerify.BatchNumber == 0 {
+		return nil, errGenesisBatch
+	}
+
+	// the previous batch of batch 1 is the genesis batch 0, stored in the
+	// state with the genesis state root
+	previousBatch, ok := a.batchCache.previous(batchToVerify)
+	if !ok {
+		var err error
+		previousBatch, err = a.State.GetBatchByNumber(ctx, batchToVerify.BatchNumber-1, nil)
+		if err != nil && err != state.ErrStateNotSynchronized {
+			return nil, fmt.Errorf("failed to get previous batch, err: %v", err)
+		}
+		if previousBatch == nil {
+			// the previous batch is not synchronized yet, the batch will be
+			// retried once it is
+			return nil, fmt.Errorf("failed to get previous batch %d, %w", batchToVerify.BatchNumber-1, state.ErrStateNotSynchronized)
+		}
+		a.batchCache.add(previousBatch)
 	}
+	// the batch to verify is usually the previous batch of the next one
+	a.batchCache.add(batchToVerify)
 
 	inputProver := &pb.InputProver{
 		PublicInputs: &pb.PublicInputs{
@@ -1030,43 +1874,191 @@ func (hc *healthChecker) Watch(req *grpchealth.HealthCheckRequest, server grpche
 func (a *Aggregator) handleMonitoredTxResult(result ethtxmanager.MonitoredTxResult) {
 	resLog := log.WithFields("owner", ethTxManagerOwner, "txId", result.ID)
 	if result.Status == ethtxmanager.MonitoredTxStatusFailed {
-		resLog.Fatal("failed to send batch verification, TODO: review this fatal and define what to do in this case")
+		a.reportFatal(fmt.Errorf("failed to send batch verification, tx %s", result.ID))
+		return
 	}
 
-	// monitoredIDFormat: "proof-from-%v-to-%v"
-	idSlice := strings.Split(result.ID, "-")
-	proofBatchNumberStr := idSlice[2]
-	proofBatchNumber, err := strconv.ParseUint(proofBatchNumberStr, encoding.Base10, 0)
+	proofBatchNumber, proofBatchNumberFinal, err := parseMonitoredTxID(result.ID)
 	if err != nil {
-		resLog.Errorf("failed to read final proof batch number from monitored tx: %v", err)
-	}
-
-	proofBatchNumberFinalStr := idSlice[4]
-	proofBatchNumberFinal, err := strconv.ParseUint(proofBatchNumberFinalStr, encoding.Base10, 0)
-	if err != nil {
-		resLog.Errorf("failed to read final proof batch number final from monitored tx: %v", err)
+		resLog.Errorf("failed to read final proof batch numbers from monitored tx: %v", err)
+		return
 	}
 
 	log := log.WithFields("txId", result.ID, "batches", fmt.Sprintf("%d-%d", proofBatchNumber, proofBatchNumberFinal))
 	log.Info("Final proof verified")
 
+	a.checkProvingSLA(a.ctx, proofBatchNumber, proofBatchNumberFinal)
+
 	// wait for the synchronizer to catch up the verified batches
 	log.Debug("A final proof has been sent, waiting for the network to be synced")
-	for !a.isSynced(a.ctx, &proofBatchNumberFinal) {
-		log.Info("Waiting for synchronizer to sync...")
-		time.Sleep(a.cfg.RetryTime.Duration)
+	if err := a.waitSynced(a.ctx, &proofBatchNumberFinal); err != nil {
+		return
+	}
+
+	if a.cfg.CleanupConfirmationBlocks == 0 {
+		a.cleanupVerifiedProofs(proofBatchNumberFinal)
+		return
 	}
 
+	// the confirmations are waited in the background, so the results of the
+	// following verifications are not held back
+	go func() {
+		if a.waitCleanupConfirmations(result) {
+			a.cleanupVerifiedProofs(proofBatchNumberFinal)
+		}
+	}()
+}
+
+// cleanupVerifiedProofs deletes the recursive proofs up to the provided
+// verified batch.
+func (a *Aggregator) cleanupVerifiedProofs(batchNumberFinal uint64) {
 	// network is synced with the final proof, we can safely delete all recursive
 	// proofs up to the last synced batch
-	err = a.State.CleanupGeneratedProofs(a.ctx, proofBatchNumberFinal, nil)
+	err := a.State.CleanupGeneratedProofs(a.ctx, batchNumberFinal, nil)
 	if err != nil {
 		log.Errorf("Failed to store proof aggregation result: %v", err)
 	}
+	a.staleProofs.prune(batchNumberFinal)
+}
+
+// checkProvingSLA reports a breach of the ProvingSLA if the first batch of the
+// verified range, the one sequenced the earliest, was sequenced longer than
+// ProvingSLA ago.
+func (a *Aggregator) checkProvingSLA(ctx context.Context, batchNumber, batchNumberFinal uint64) {
+	if a.cfg.ProvingSLA.Duration == 0 {
+		return
+	}
+
+	sequencedAt, err := a.State.GetVirtualBatchTimestamp(ctx, batchNumber, nil)
+	if err != nil {
+		log.Errorf("Failed to get the sequencing time of batch %d: %v", batchNumber, err)
+		return
+	}
+	if elapsed := a.clock.Now().Sub(sequencedAt); elapsed > a.cfg.ProvingSLA.Duration {
+		log.Warnf("Proving SLA of %v breached, batches %d-%d verified %v after being sequenced",
+			a.cfg.ProvingSLA.Duration, batchNumber, batchNumberFinal, elapsed)
+		metrics.ProvingSLABreach()
+	}
+}
+
+// waitCleanupConfirmations waits until the block including the mined tx of
+// the provided batch verification is buried by CleanupConfirmationBlocks L1
+// blocks, so the verified proofs are not cleaned up while the verification
+// can still be reorged out. It returns false if the aggregator is stopped
+// while waiting.
+func (a *Aggregator) waitCleanupConfirmations(result ethtxmanager.MonitoredTxResult) bool {
+	log := log.WithFields("txId", result.ID)
+
+	var txBlockNumber uint64
+	for _, txResult := range result.Txs {
+		receipt := txResult.Receipt
+		if receipt != nil && receipt.Status == ethTypes.ReceiptStatusSuccessful && receipt.BlockNumber != nil {
+			txBlockNumber = receipt.BlockNumber.Uint64()
+			break
+		}
+	}
+	if txBlockNumber == 0 {
+		log.Warn("No mined batch verification tx found, skipping the cleanup confirmations wait")
+		return true
+	}
+
+	confirmedBlockNumber := txBlockNumber + a.cfg.CleanupConfirmationBlocks
+	for {
+		var latestBlockNumber uint64
+		err := a.l1Breaker.call(a.clock.Now, func() error {
+			var err error
+			latestBlockNumber, err = a.Ethman.GetLatestBlockNumber(a.ctx)
+			return err
+		})
+		if err != nil {
+			log.Warnf("Failed to get latest L1 block number: %v", err)
+		} else if latestBlockNumber >= confirmedBlockNumber {
+			return true
+		} else {
+			log.Infof("Waiting for batch verification confirmations before cleaning up proofs, latest block: %d, waiting for block: %d",
+				latestBlockNumber, confirmedBlockNumber)
+		}
+
+		select {
+		case <-a.ctx.Done():
+			return false
+		case <-a.clock.After(a.cfg.RetryTime.Duration):
+		}
+	}
+}
+
+// monitorOldestUnprovenBatch updates every OldestUnprovenBatchAgeInterval the
+// metric with the age of the oldest virtual batch pending to be proved.
+func (a *Aggregator) monitorOldestUnprovenBatch() {
+	if a.cfg.OldestUnprovenBatchAgeInterval.Duration == 0 {
+		return
+	}
+
+	for {
+		select {
+		case <-a.ctx.Done():
+			return
+		case <-a.clock.After(a.cfg.OldestUnprovenBatchAgeInterval.Duration):
+			if err := a.updateOldestUnprovenBatchAge(a.ctx); err != nil {
+				log.Errorf("Failed to update oldest unproven batch age: %v", err)
+			}
+		}
+	}
+}
+
+// updateOldestUnprovenBatchAge sets the metric with the age of the oldest
+// virtual batch pending to be proved, or 0 if there is none.
+func (a *Aggregator) updateOldestUnprovenBatchAge(ctx context.Context) error {
+	var lastVerifiedBatchNum uint64
+	lastVerifiedBatch, err := a.State.GetLastVerifiedBatch(ctx, nil)
+	if err != nil && !errors.Is(err, state.ErrNotFound) {
+		return fmt.Errorf("failed to get last verified batch, %w", err)
+	}
+	if lastVerifiedBatch != nil {
+		lastVerifiedBatchNum = lastVerifiedBatch.BatchNumber
+	}
+
+	batch, err := a.State.GetVirtualBatchToProve(ctx, lastVerifiedBatchNum, nil)
+	if errors.Is(err, state.ErrNotFound) {
+		metrics.OldestUnprovenBatchAge(0)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get virtual batch to prove, %w", err)
+	}
+
+	metrics.OldestUnprovenBatchAge(a.clock.Now().Sub(batch.Timestamp))
+	return nil
 }
 
 func buildMonitoredTxID(batchNumber, batchNumberFinal uint64) string {
-	return fmt.Sprintf(monitoredIDFormat, batchNumber, batchNumberFinal)
+	return fmt.Sprintf(monitoredIDFormat,
+		strconv.FormatUint(batchNumber, monitoredIDBatchNumberBase),
+		strconv.FormatUint(batchNumberFinal, monitoredIDBatchNumberBase))
+}
+
+// parseMonitoredTxID returns the batch numbers of the monitored tx ID built
+// by buildMonitoredTxID, failing if the ID is not in the expected format.
+func parseMonitoredTxID(id string) (batchNumber, batchNumberFinal uint64, err error) {
+	// monitoredIDFormat: "proof-from-%s-to-%s"
+	idSlice := strings.Split(id, "-")
+	if len(idSlice) != strings.Count(monitoredIDFormat, "-")+1 {
+		return 0, 0, fmt.Errorf("invalid monitored tx ID %q", id)
+	}
+	batchNumber, err = strconv.ParseUint(idSlice[2], monitoredIDBatchNumberBase, 0)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid batch number in monitored tx ID %q: %w", id, err)
+	}
+	batchNumberFinal, err = strconv.ParseUint(idSlice[4], monitoredIDBatchNumberBase, 0)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid batch number final in monitored tx ID %q: %w", id, err)
+	}
+	// the numbers must round-trip, rejecting IDs with the same shape but a
+	// different encoding, like leading zeros or signs
+	if buildMonitoredTxID(batchNumber, batchNumberFinal) != id {
+		return 0, 0, fmt.Errorf("invalid monitored tx ID %q", id)
+	}
+	return batchNumber, batchNumberFinal, nil
 }
 
 func (a *Aggregator) cleanupLockedProofs() {
@@ -1074,7 +2066,8 @@ func (a *Aggregator) cleanupLockedProofs() {
 		select {
 		case <-a.ctx.Done():
 			return
-		case <-time.After(a.TimeCleanupLockedProofs.Duration):
+		case <-a.clock.After(a.TimeCleanupLockedProofs.Duration):
+			a.logStaleProofs(a.ctx)
 			n, err := a.State.CleanupLockedProofs(a.ctx, a.cfg.GeneratingProofCleanupThreshold, nil)
 			if err != nil {
 				log.Errorf("Failed to cleanup locked proofs: %v", err)
@@ -1088,6 +2081,34 @@ func (a *Aggregator) cleanupLockedProofs() {
 	}
 }
 
+// logStaleProofs logs the proofs locked in generating state for more than the
+// GeneratingProofCleanupThreshold, which are about to be reclaimed.
+func (a *Aggregator) logStaleProofs(ctx context.Context) {
+	threshold, err := time.ParseDuration(a.cfg.GeneratingProofCleanupThreshold)
+	if err != nil {
+		log.Errorf("Failed to parse generating proof cleanup threshold: %v", err)
+		return
+	}
+
+	proofs, err := a.State.GetGeneratingProofs(ctx, threshold, nil)
+	if err != nil {
+		log.Errorf("Failed to get generating proofs: %v", err)
+		return
+	}
+
+	for _, proof := range proofs {
+		var prover, proverID string
+		if proof.Prover != nil {
+			prover = *proof.Prover
+		}
+		if proof.ProverID != nil {
+			proverID = *proof.ProverID
+		}
+		log.Warnf("Reclaiming proof %d-%d locked by prover %s (%s) for %v",
+			proof.BatchNumber, proof.BatchNumberFinal, prover, proverID, a.clock.Now().Sub(*proof.GeneratingSince).Round(time.Second))
+	}
+}
+
 // FirstToUpper returns the string passed as argument with the first letter in
 // uppercase.
 func FirstToUpper(s string) string {
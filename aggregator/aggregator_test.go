@@ -4,22 +4,38 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"math"
 	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/0xPolygonHermez/zkevm-node/aggregator/metrics"
 	"github.com/0xPolygonHermez/zkevm-node/aggregator/mocks"
 	"github.com/0xPolygonHermez/zkevm-node/aggregator/pb"
 	configTypes "github.com/0xPolygonHermez/zkevm-node/config/types"
+	ethman "github.com/0xPolygonHermez/zkevm-node/etherman"
 	ethmanTypes "github.com/0xPolygonHermez/zkevm-node/etherman/types"
 	"github.com/0xPolygonHermez/zkevm-node/ethtxmanager"
+	"github.com/0xPolygonHermez/zkevm-node/log"
+	zkevmMetrics "github.com/0xPolygonHermez/zkevm-node/metrics"
 	"github.com/0xPolygonHermez/zkevm-node/state"
 	"github.com/0xPolygonHermez/zkevm-node/test/testutils"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	ethTypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
 )
 
 type mox struct {
@@ -29,6 +45,17 @@ type mox struct {
 	proverMock   *mocks.ProverMock
 }
 
+// newTestConfig returns a valid config for the tests to customize.
+func newTestConfig() Config {
+	return Config{
+		RetryTime:                   configTypes.NewDuration(time.Millisecond),
+		ProofStatePollingInterval:   configTypes.NewDuration(time.Millisecond),
+		CleanupLockedProofsInterval: configTypes.NewDuration(time.Minute),
+		TxProfitabilityCheckerType:  ProfitabilityAcceptAll,
+		SenderAddress:               common.BytesToAddress([]byte("from")).Hex(),
+	}
+}
+
 func TestSendFinalProof(t *testing.T) {
 	require := require.New(t)
 	assert := assert.New(t)
@@ -53,13 +80,22 @@ func TestSendFinalProof(t *testing.T) {
 		BatchNumber:      batchNum,
 		BatchNumberFinal: batchNumFinal,
 	}
-	finalProof := &pb.FinalProof{}
-	cfg := Config{SenderAddress: from.Hex()}
+	finalProof := &pb.FinalProof{
+		Public: &pb.PublicInputsExtended{
+			NewStateRoot:     finalBatch.StateRoot.Bytes(),
+			NewLocalExitRoot: finalBatch.LocalExitRoot.Bytes(),
+		},
+	}
+	cfg := newTestConfig()
+	cfg.SenderAddress = from.Hex()
+	exportDir := t.TempDir()
 
 	testCases := []struct {
-		name    string
-		setup   func(mox, *Aggregator)
-		asserts func(*Aggregator)
+		name        string
+		localVerify bool
+		finalProof  *pb.FinalProof
+		setup       func(mox, *Aggregator)
+		asserts     func(*Aggregator)
 	}{
 		{
 			name: "GetBatchByNumber error",
@@ -145,6 +181,115 @@ func TestSendFinalProof(t *testing.T) {
 				assert.False(a.verifyingProof)
 			},
 		},
+		{
+			name: "verification calldata exported",
+			setup: func(m mox, a *Aggregator) {
+				a.cfg.ExportVerifyCalldataDir = exportDir
+				m.stateMock.On("GetBatchByNumber", mock.Anything, batchNumFinal, nil).Return(&finalBatch, nil).Once()
+				expectedInputs := ethmanTypes.FinalProofInputs{
+					FinalProof:       finalProof,
+					NewLocalExitRoot: finalBatch.LocalExitRoot.Bytes(),
+					NewStateRoot:     finalBatch.StateRoot.Bytes(),
+				}
+				m.etherman.On("BuildTrustedVerifyBatchesTxData", batchNum-1, batchNumFinal, &expectedInputs).Return(&to, data, nil).Once()
+				monitoredTxID := buildMonitoredTxID(batchNum, batchNumFinal)
+				m.ethTxManager.On("Add", mock.Anything, ethTxManagerOwner, monitoredTxID, from, &to, value, data, nil).Return(errBanana).Once()
+				m.stateMock.On("UpdateGeneratedProof", mock.Anything, recursiveProof, nil).Run(func(args mock.Arguments) {
+					// test is done, stop the sendFinalProof method
+					a.exit()
+				}).Return(nil).Once()
+			},
+			asserts: func(a *Aggregator) {
+				b, err := os.ReadFile(filepath.Join(exportDir, "verify-batches-23-42.json"))
+				require.NoError(err)
+				expected := fmt.Sprintf(`{"to":"%s","data":"%s"}`, strings.ToLower(to.Hex()), hexutil.Encode(data))
+				assert.JSONEq(expected, string(b))
+			},
+		},
+		{
+			name: "final proof roots not matching the batch",
+			finalProof: &pb.FinalProof{
+				Public: &pb.PublicInputsExtended{
+					NewStateRoot:     common.BytesToHash([]byte("otherStateRoot")).Bytes(),
+					NewLocalExitRoot: finalBatch.LocalExitRoot.Bytes(),
+				},
+			},
+			setup: func(m mox, a *Aggregator) {
+				m.stateMock.On("GetBatchByNumber", mock.Anything, batchNumFinal, nil).Return(&finalBatch, nil).Once()
+				m.stateMock.On("DeleteGeneratedProofs", mock.Anything, batchNum, batchNumFinal, nil).Run(func(args mock.Arguments) {
+					// test is done, stop the sendFinalProof method
+					a.exit()
+				}).Return(nil).Once()
+			},
+			asserts: func(a *Aggregator) {
+				assert.False(a.verifyingProof)
+			},
+		},
+		{
+			name:        "local verification rejects the proof",
+			localVerify: true,
+			setup: func(m mox, a *Aggregator) {
+				m.stateMock.On("GetBatchByNumber", mock.Anything, batchNumFinal, nil).Return(&finalBatch, nil).Once()
+				expectedInputs := ethmanTypes.FinalProofInputs{
+					FinalProof:       finalProof,
+					NewLocalExitRoot: finalBatch.LocalExitRoot.Bytes(),
+					NewStateRoot:     finalBatch.StateRoot.Bytes(),
+				}
+				m.etherman.On("BuildTrustedVerifyBatchesTxData", batchNum-1, batchNumFinal, &expectedInputs).Return(&to, data, nil).Once()
+				m.etherman.On("CheckTrustedVerifyBatches", mock.Anything, from, batchNum-1, batchNumFinal, &expectedInputs).Return(ethman.ErrInvalidProof).Once()
+				m.stateMock.On("DeleteGeneratedProofs", mock.Anything, batchNum, batchNumFinal, nil).Run(func(args mock.Arguments) {
+					// test is done, stop the sendFinalProof method
+					a.exit()
+				}).Return(nil).Once()
+			},
+			asserts: func(a *Aggregator) {
+				assert.False(a.verifyingProof)
+			},
+		},
+		{
+			name:        "local verification error",
+			localVerify: true,
+			setup: func(m mox, a *Aggregator) {
+				m.stateMock.On("GetBatchByNumber", mock.Anything, batchNumFinal, nil).Return(&finalBatch, nil).Once()
+				expectedInputs := ethmanTypes.FinalProofInputs{
+					FinalProof:       finalProof,
+					NewLocalExitRoot: finalBatch.LocalExitRoot.Bytes(),
+					NewStateRoot:     finalBatch.StateRoot.Bytes(),
+				}
+				m.etherman.On("BuildTrustedVerifyBatchesTxData", batchNum-1, batchNumFinal, &expectedInputs).Return(&to, data, nil).Once()
+				m.etherman.On("CheckTrustedVerifyBatches", mock.Anything, from, batchNum-1, batchNumFinal, &expectedInputs).Return(errBanana).Once()
+				m.stateMock.On("UpdateGeneratedProof", mock.Anything, recursiveProof, nil).Run(func(args mock.Arguments) {
+					// test is done, stop the sendFinalProof method
+					a.exit()
+				}).Return(nil).Once()
+			},
+			asserts: func(a *Aggregator) {
+				assert.False(a.verifyingProof)
+			},
+		},
+		{
+			name:        "local verification accepts the proof",
+			localVerify: true,
+			setup: func(m mox, a *Aggregator) {
+				m.stateMock.On("GetBatchByNumber", mock.Anything, batchNumFinal, nil).Return(&finalBatch, nil).Once()
+				expectedInputs := ethmanTypes.FinalProofInputs{
+					FinalProof:       finalProof,
+					NewLocalExitRoot: finalBatch.LocalExitRoot.Bytes(),
+					NewStateRoot:     finalBatch.StateRoot.Bytes(),
+				}
+				m.etherman.On("BuildTrustedVerifyBatchesTxData", batchNum-1, batchNumFinal, &expectedInputs).Return(&to, data, nil).Once()
+				m.etherman.On("CheckTrustedVerifyBatches", mock.Anything, from, batchNum-1, batchNumFinal, &expectedInputs).Return(nil).Once()
+				monitoredTxID := buildMonitoredTxID(batchNum, batchNumFinal)
+				m.ethTxManager.On("Add", mock.Anything, ethTxManagerOwner, monitoredTxID, from, &to, value, data, nil).Run(func(args mock.Arguments) {
+					// test is done, stop the sendFinalProof method
+					a.exit()
+				}).Return(errBanana).Once()
+				m.stateMock.On("UpdateGeneratedProof", mock.Anything, recursiveProof, nil).Return(nil).Once()
+			},
+			asserts: func(a *Aggregator) {
+				assert.False(a.verifyingProof)
+			},
+		},
 		{
 			name: "nominal case",
 			setup: func(m mox, a *Aggregator) {
@@ -190,6 +335,8 @@ func TestSendFinalProof(t *testing.T) {
 			stateMock := mocks.NewStateMock(t)
 			ethTxManager := mocks.NewEthTxManager(t)
 			etherman := mocks.NewEtherman(t)
+			cfg := cfg
+			cfg.LocalVerifyBeforeSubmit = tc.localVerify
 			a, err := New(cfg, stateMock, ethTxManager, etherman)
 			require.NoError(err)
 			a.ctx, a.exit = context.WithCancel(context.Background())
@@ -208,6 +355,9 @@ func TestSendFinalProof(t *testing.T) {
 					recursiveProof: recursiveProof,
 					finalProof:     finalProof,
 				}
+				if tc.finalProof != nil {
+					finalMsg.finalProof = tc.finalProof
+				}
 				a.finalProof <- finalMsg
 			}()
 
@@ -224,13 +374,12 @@ func TestTryAggregateProofs(t *testing.T) {
 	require := require.New(t)
 	assert := assert.New(t)
 	errBanana := errors.New("banana")
-	cfg := Config{
-		VerifyProofInterval: configTypes.NewDuration(10000000),
-	}
+	cfg := newTestConfig()
+	cfg.VerifyProofInterval = configTypes.NewDuration(10000000)
 	proofID := "proofId"
 	proverName := "proverName"
 	proverID := "proverID"
-	recursiveProof := "recursiveProof"
+	recursiveProof := `{"proof":"recursiveProof"}`
 	proverCtx := context.WithValue(context.Background(), "owner", "prover") //nolint:staticcheck
 	matchProverCtxFn := func(ctx context.Context) bool { return ctx.Value("owner") == "prover" }
 	matchAggregatorCtxFn := func(ctx context.Context) bool { return ctx.Value("owner") == "aggregator" }
@@ -255,7 +404,7 @@ func TestTryAggregateProofs(t *testing.T) {
 				m.proverMock.On("Name").Return(proverName).Twice()
 				m.proverMock.On("ID").Return(proverID).Twice()
 				m.proverMock.On("Addr").Return("addr")
-				m.stateMock.On("GetProofsToAggregate", mock.MatchedBy(matchProverCtxFn), nil).Return(nil, nil, errBanana).Once()
+				m.stateMock.On("GetProofsToAggregate", mock.MatchedBy(matchProverCtxFn), uint64(0), cfg.MaxBatchesPerFinalProof, nil).Return(nil, nil, errBanana).Once()
 			},
 			asserts: func(result bool, a *Aggregator, err error) {
 				assert.False(result)
@@ -268,7 +417,21 @@ func TestTryAggregateProofs(t *testing.T) {
 				m.proverMock.On("Name").Return(proverName).Twice()
 				m.proverMock.On("ID").Return(proverID).Twice()
 				m.proverMock.On("Addr").Return("addr")
-				m.stateMock.On("GetProofsToAggregate", mock.MatchedBy(matchProverCtxFn), nil).Return(nil, nil, state.ErrNotFound).Once()
+				m.stateMock.On("GetProofsToAggregate", mock.MatchedBy(matchProverCtxFn), uint64(0), cfg.MaxBatchesPerFinalProof, nil).Return(nil, nil, state.ErrNotFound).Once()
+			},
+			asserts: func(result bool, a *Aggregator, err error) {
+				assert.False(result)
+				assert.NoError(err)
+			},
+		},
+		{
+			name: "aggregation capped to max batches per final proof",
+			setup: func(m mox, a *Aggregator) {
+				a.cfg.MaxBatchesPerFinalProof = 10
+				m.proverMock.On("Name").Return(proverName).Twice()
+				m.proverMock.On("ID").Return(proverID).Twice()
+				m.proverMock.On("Addr").Return("addr")
+				m.stateMock.On("GetProofsToAggregate", mock.MatchedBy(matchProverCtxFn), uint64(0), uint64(10), nil).Return(nil, nil, state.ErrNotFound).Once()
 			},
 			asserts: func(result bool, a *Aggregator, err error) {
 				assert.False(result)
@@ -284,7 +447,7 @@ func TestTryAggregateProofs(t *testing.T) {
 				dbTx := &mocks.DbTxMock{}
 				dbTx.On("Rollback", mock.MatchedBy(matchProverCtxFn)).Return(nil).Once()
 				m.stateMock.On("BeginStateTransaction", mock.MatchedBy(matchProverCtxFn)).Return(dbTx, nil).Once()
-				m.stateMock.On("GetProofsToAggregate", mock.MatchedBy(matchProverCtxFn), nil).Return(&proof1, &proof2, nil).Once()
+				m.stateMock.On("GetProofsToAggregate", mock.MatchedBy(matchProverCtxFn), uint64(0), cfg.MaxBatchesPerFinalProof, nil).Return(&proof1, &proof2, nil).Once()
 				m.stateMock.
 					On("UpdateGeneratedProof", mock.MatchedBy(matchProverCtxFn), &proof1, dbTx).
 					Run(func(args mock.Arguments) {
@@ -307,7 +470,58 @@ func TestTryAggregateProofs(t *testing.T) {
 				dbTx := &mocks.DbTxMock{}
 				lockProofsTxBegin := m.stateMock.On("BeginStateTransaction", mock.MatchedBy(matchProverCtxFn)).Return(dbTx, nil).Once()
 				lockProofsTxCommit := dbTx.On("Commit", mock.MatchedBy(matchProverCtxFn)).Return(nil).Once()
-				m.stateMock.On("GetProofsToAggregate", mock.MatchedBy(matchProverCtxFn), nil).Return(&proof1, &proof2, nil).Once()
+				m.stateMock.On("GetProofsToAggregate", mock.MatchedBy(matchProverCtxFn), uint64(0), cfg.MaxBatchesPerFinalProof, nil).Return(&proof1, &proof2, nil).Once()
+				proof1GeneratingTrueCall := m.stateMock.
+					On("UpdateGeneratedProof", mock.MatchedBy(matchProverCtxFn), &proof1, dbTx).
+					Run(func(args mock.Arguments) {
+						assert.NotNil(args[1].(*state.Proof).GeneratingSince)
+					}).
+					Return(nil).
+					Once()
+				proof2GeneratingTrueCall := m.stateMock.
+					On("UpdateGeneratedProof", mock.MatchedBy(matchProverCtxFn), &proof2, dbTx).
+					Run(func(args mock.Arguments) {
+						assert.NotNil(args[1].(*state.Proof).GeneratingSince)
+					}).
+					Return(nil).
+					Once()
+				m.proverMock.On("AggregatedProof", proof1.Proof, proof2.Proof).Return(nil, errBanana).Once()
+				m.stateMock.On("BeginStateTransaction", mock.MatchedBy(matchAggregatorCtxFn)).Return(dbTx, nil).Once().NotBefore(lockProofsTxBegin)
+				m.stateMock.
+					On("UpdateGeneratedProof", mock.MatchedBy(matchAggregatorCtxFn), &proof1, dbTx).
+					Run(func(args mock.Arguments) {
+						assert.Nil(args[1].(*state.Proof).GeneratingSince)
+					}).
+					Return(nil).
+					Once().
+					NotBefore(proof1GeneratingTrueCall)
+				m.stateMock.
+					On("UpdateGeneratedProof", mock.MatchedBy(matchAggregatorCtxFn), &proof2, dbTx).
+					Run(func(args mock.Arguments) {
+						assert.Nil(args[1].(*state.Proof).GeneratingSince)
+					}).
+					Return(nil).
+					Once().
+					NotBefore(proof2GeneratingTrueCall)
+				dbTx.On("Commit", mock.MatchedBy(matchAggregatorCtxFn)).Return(nil).Once().NotBefore(lockProofsTxCommit)
+			},
+			asserts: func(result bool, a *Aggregator, err error) {
+				assert.False(result)
+				assert.ErrorIs(err, errBanana)
+			},
+		},
+		{
+			name: "AggregatedProof prover error quarantines the pair after max retries",
+			setup: func(m mox, a *Aggregator) {
+				a.aggrQuarantine = newAggregationQuarantine(2)
+				a.aggrQuarantine.recordFailure(batchNum, batchNumFinal)
+				m.proverMock.On("Name").Return(proverName).Twice()
+				m.proverMock.On("ID").Return(proverID).Twice()
+				m.proverMock.On("Addr").Return("addr")
+				dbTx := &mocks.DbTxMock{}
+				lockProofsTxBegin := m.stateMock.On("BeginStateTransaction", mock.MatchedBy(matchProverCtxFn)).Return(dbTx, nil).Once()
+				lockProofsTxCommit := dbTx.On("Commit", mock.MatchedBy(matchProverCtxFn)).Return(nil).Once()
+				m.stateMock.On("GetProofsToAggregate", mock.MatchedBy(matchProverCtxFn), uint64(0), cfg.MaxBatchesPerFinalProof, nil).Return(&proof1, &proof2, nil).Once()
 				proof1GeneratingTrueCall := m.stateMock.
 					On("UpdateGeneratedProof", mock.MatchedBy(matchProverCtxFn), &proof1, dbTx).
 					Run(func(args mock.Arguments) {
@@ -345,6 +559,23 @@ func TestTryAggregateProofs(t *testing.T) {
 			asserts: func(result bool, a *Aggregator, err error) {
 				assert.False(result)
 				assert.ErrorIs(err, errBanana)
+				assert.True(a.aggrQuarantine.isQuarantined(batchNum, batchNumFinal))
+			},
+		},
+		{
+			name: "quarantined pair is not aggregated",
+			setup: func(m mox, a *Aggregator) {
+				a.aggrQuarantine = newAggregationQuarantine(1)
+				a.aggrQuarantine.recordFailure(batchNum, batchNumFinal)
+				m.proverMock.On("Name").Return(proverName).Twice()
+				m.proverMock.On("ID").Return(proverID).Twice()
+				m.proverMock.On("Addr").Return("addr")
+				m.stateMock.On("GetProofsToAggregate", mock.MatchedBy(matchProverCtxFn), uint64(0), cfg.MaxBatchesPerFinalProof, nil).Return(&proof1, &proof2, nil).Once()
+				m.stateMock.On("GetProofsToAggregate", mock.MatchedBy(matchProverCtxFn), batchNum+1, cfg.MaxBatchesPerFinalProof, nil).Return(nil, nil, state.ErrNotFound).Once()
+			},
+			asserts: func(result bool, a *Aggregator, err error) {
+				assert.False(result)
+				assert.NoError(err)
 			},
 		},
 		{
@@ -356,7 +587,7 @@ func TestTryAggregateProofs(t *testing.T) {
 				dbTx := &mocks.DbTxMock{}
 				lockProofsTxBegin := m.stateMock.On("BeginStateTransaction", mock.MatchedBy(matchProverCtxFn)).Return(dbTx, nil).Once()
 				lockProofsTxCommit := dbTx.On("Commit", mock.MatchedBy(matchProverCtxFn)).Return(nil).Once()
-				m.stateMock.On("GetProofsToAggregate", mock.MatchedBy(matchProverCtxFn), nil).Return(&proof1, &proof2, nil).Once()
+				m.stateMock.On("GetProofsToAggregate", mock.MatchedBy(matchProverCtxFn), uint64(0), cfg.MaxBatchesPerFinalProof, nil).Return(&proof1, &proof2, nil).Once()
 				proof1GeneratingTrueCall := m.stateMock.
 					On("UpdateGeneratedProof", mock.MatchedBy(matchProverCtxFn), &proof1, dbTx).
 					Run(func(args mock.Arguments) {
@@ -397,6 +628,106 @@ func TestTryAggregateProofs(t *testing.T) {
 				assert.ErrorIs(err, errBanana)
 			},
 		},
+		{
+			name: "WaitRecursiveProof invalid JSON proof",
+			setup: func(m mox, a *Aggregator) {
+				m.proverMock.On("Name").Return(proverName).Twice()
+				m.proverMock.On("ID").Return(proverID).Twice()
+				m.proverMock.On("Addr").Return("addr")
+				dbTx := &mocks.DbTxMock{}
+				lockProofsTxBegin := m.stateMock.On("BeginStateTransaction", mock.MatchedBy(matchProverCtxFn)).Return(dbTx, nil).Once()
+				lockProofsTxCommit := dbTx.On("Commit", mock.MatchedBy(matchProverCtxFn)).Return(nil).Once()
+				m.stateMock.On("GetProofsToAggregate", mock.MatchedBy(matchProverCtxFn), uint64(0), cfg.MaxBatchesPerFinalProof, nil).Return(&proof1, &proof2, nil).Once()
+				proof1GeneratingTrueCall := m.stateMock.
+					On("UpdateGeneratedProof", mock.MatchedBy(matchProverCtxFn), &proof1, dbTx).
+					Run(func(args mock.Arguments) {
+						assert.NotNil(args[1].(*state.Proof).GeneratingSince)
+					}).
+					Return(nil).
+					Once()
+				proof2GeneratingTrueCall := m.stateMock.
+					On("UpdateGeneratedProof", mock.MatchedBy(matchProverCtxFn), &proof2, dbTx).
+					Run(func(args mock.Arguments) {
+						assert.NotNil(args[1].(*state.Proof).GeneratingSince)
+					}).
+					Return(nil).
+					Once()
+				m.proverMock.On("AggregatedProof", proof1.Proof, proof2.Proof).Return(&proofID, nil).Once()
+				m.proverMock.On("WaitRecursiveProof", mock.MatchedBy(matchProverCtxFn), proofID).Return("invalid proof", nil).Once()
+				m.stateMock.On("BeginStateTransaction", mock.MatchedBy(matchAggregatorCtxFn)).Return(dbTx, nil).Once().NotBefore(lockProofsTxBegin)
+				m.stateMock.
+					On("UpdateGeneratedProof", mock.MatchedBy(matchAggregatorCtxFn), &proof1, dbTx).
+					Run(func(args mock.Arguments) {
+						assert.Nil(args[1].(*state.Proof).GeneratingSince)
+					}).
+					Return(nil).
+					Once().
+					NotBefore(proof1GeneratingTrueCall)
+				m.stateMock.
+					On("UpdateGeneratedProof", mock.MatchedBy(matchAggregatorCtxFn), &proof2, dbTx).
+					Run(func(args mock.Arguments) {
+						assert.Nil(args[1].(*state.Proof).GeneratingSince)
+					}).
+					Return(nil).
+					Once().
+					NotBefore(proof2GeneratingTrueCall)
+				dbTx.On("Commit", mock.MatchedBy(matchAggregatorCtxFn)).Return(nil).Once().NotBefore(lockProofsTxCommit)
+			},
+			asserts: func(result bool, a *Aggregator, err error) {
+				assert.False(result)
+				assert.ErrorIs(err, errInvalidProof)
+			},
+		},
+		{
+			name: "WaitRecursiveProof empty proof",
+			setup: func(m mox, a *Aggregator) {
+				m.proverMock.On("Name").Return(proverName).Twice()
+				m.proverMock.On("ID").Return(proverID).Twice()
+				m.proverMock.On("Addr").Return("addr")
+				dbTx := &mocks.DbTxMock{}
+				lockProofsTxBegin := m.stateMock.On("BeginStateTransaction", mock.MatchedBy(matchProverCtxFn)).Return(dbTx, nil).Once()
+				lockProofsTxCommit := dbTx.On("Commit", mock.MatchedBy(matchProverCtxFn)).Return(nil).Once()
+				m.stateMock.On("GetProofsToAggregate", mock.MatchedBy(matchProverCtxFn), uint64(0), cfg.MaxBatchesPerFinalProof, nil).Return(&proof1, &proof2, nil).Once()
+				proof1GeneratingTrueCall := m.stateMock.
+					On("UpdateGeneratedProof", mock.MatchedBy(matchProverCtxFn), &proof1, dbTx).
+					Run(func(args mock.Arguments) {
+						assert.NotNil(args[1].(*state.Proof).GeneratingSince)
+					}).
+					Return(nil).
+					Once()
+				proof2GeneratingTrueCall := m.stateMock.
+					On("UpdateGeneratedProof", mock.MatchedBy(matchProverCtxFn), &proof2, dbTx).
+					Run(func(args mock.Arguments) {
+						assert.NotNil(args[1].(*state.Proof).GeneratingSince)
+					}).
+					Return(nil).
+					Once()
+				m.proverMock.On("AggregatedProof", proof1.Proof, proof2.Proof).Return(&proofID, nil).Once()
+				m.proverMock.On("WaitRecursiveProof", mock.MatchedBy(matchProverCtxFn), proofID).Return("", nil).Once()
+				m.stateMock.On("BeginStateTransaction", mock.MatchedBy(matchAggregatorCtxFn)).Return(dbTx, nil).Once().NotBefore(lockProofsTxBegin)
+				m.stateMock.
+					On("UpdateGeneratedProof", mock.MatchedBy(matchAggregatorCtxFn), &proof1, dbTx).
+					Run(func(args mock.Arguments) {
+						assert.Nil(args[1].(*state.Proof).GeneratingSince)
+					}).
+					Return(nil).
+					Once().
+					NotBefore(proof1GeneratingTrueCall)
+				m.stateMock.
+					On("UpdateGeneratedProof", mock.MatchedBy(matchAggregatorCtxFn), &proof2, dbTx).
+					Run(func(args mock.Arguments) {
+						assert.Nil(args[1].(*state.Proof).GeneratingSince)
+					}).
+					Return(nil).
+					Once().
+					NotBefore(proof2GeneratingTrueCall)
+				dbTx.On("Commit", mock.MatchedBy(matchAggregatorCtxFn)).Return(nil).Once().NotBefore(lockProofsTxCommit)
+			},
+			asserts: func(result bool, a *Aggregator, err error) {
+				assert.False(result)
+				assert.ErrorIs(err, errEmptyProof)
+			},
+		},
 		{
 			name: "unlockProofsToAggregate error after WaitRecursiveProof prover error",
 			setup: func(m mox, a *Aggregator) {
@@ -406,7 +737,7 @@ func TestTryAggregateProofs(t *testing.T) {
 				dbTx := &mocks.DbTxMock{}
 				lockProofsTxBegin := m.stateMock.On("BeginStateTransaction", mock.MatchedBy(matchProverCtxFn)).Return(dbTx, nil).Once()
 				dbTx.On("Commit", mock.MatchedBy(matchProverCtxFn)).Return(nil).Once()
-				m.stateMock.On("GetProofsToAggregate", mock.MatchedBy(matchProverCtxFn), nil).Return(&proof1, &proof2, nil).Once()
+				m.stateMock.On("GetProofsToAggregate", mock.MatchedBy(matchProverCtxFn), uint64(0), cfg.MaxBatchesPerFinalProof, nil).Return(&proof1, &proof2, nil).Once()
 				proof1GeneratingTrueCall := m.stateMock.
 					On("UpdateGeneratedProof", mock.MatchedBy(matchProverCtxFn), &proof1, dbTx).
 					Run(func(args mock.Arguments) {
@@ -448,7 +779,7 @@ func TestTryAggregateProofs(t *testing.T) {
 				dbTx := &mocks.DbTxMock{}
 				lockProofsTxBegin := m.stateMock.On("BeginStateTransaction", mock.MatchedBy(matchProverCtxFn)).Return(dbTx, nil).Twice()
 				lockProofsTxCommit := dbTx.On("Commit", mock.MatchedBy(matchProverCtxFn)).Return(nil).Once()
-				m.stateMock.On("GetProofsToAggregate", mock.MatchedBy(matchProverCtxFn), nil).Return(&proof1, &proof2, nil).Once()
+				m.stateMock.On("GetProofsToAggregate", mock.MatchedBy(matchProverCtxFn), uint64(0), cfg.MaxBatchesPerFinalProof, nil).Return(&proof1, &proof2, nil).Once()
 				proof1GeneratingTrueCall := m.stateMock.
 					On("UpdateGeneratedProof", mock.MatchedBy(matchProverCtxFn), &proof1, dbTx).
 					Run(func(args mock.Arguments) {
@@ -500,7 +831,7 @@ func TestTryAggregateProofs(t *testing.T) {
 				dbTx := &mocks.DbTxMock{}
 				lockProofsTxBegin := m.stateMock.On("BeginStateTransaction", mock.MatchedBy(matchProverCtxFn)).Return(dbTx, nil).Twice()
 				lockProofsTxCommit := dbTx.On("Commit", mock.MatchedBy(matchProverCtxFn)).Return(nil).Once()
-				m.stateMock.On("GetProofsToAggregate", mock.MatchedBy(matchProverCtxFn), nil).Return(&proof1, &proof2, nil).Once()
+				m.stateMock.On("GetProofsToAggregate", mock.MatchedBy(matchProverCtxFn), uint64(0), cfg.MaxBatchesPerFinalProof, nil).Return(&proof1, &proof2, nil).Once()
 				proof1GeneratingTrueCall := m.stateMock.
 					On("UpdateGeneratedProof", mock.MatchedBy(matchProverCtxFn), &proof1, dbTx).
 					Run(func(args mock.Arguments) {
@@ -553,7 +884,7 @@ func TestTryAggregateProofs(t *testing.T) {
 				dbTx := &mocks.DbTxMock{}
 				m.stateMock.On("BeginStateTransaction", mock.MatchedBy(matchProverCtxFn)).Return(dbTx, nil).Twice()
 				dbTx.On("Commit", mock.MatchedBy(matchProverCtxFn)).Return(nil).Twice()
-				m.stateMock.On("GetProofsToAggregate", mock.MatchedBy(matchProverCtxFn), nil).Return(&proof1, &proof2, nil).Once()
+				m.stateMock.On("GetProofsToAggregate", mock.MatchedBy(matchProverCtxFn), uint64(0), cfg.MaxBatchesPerFinalProof, nil).Return(&proof1, &proof2, nil).Once()
 				m.stateMock.
 					On("UpdateGeneratedProof", mock.MatchedBy(matchProverCtxFn), &proof1, dbTx).
 					Run(func(args mock.Arguments) {
@@ -608,16 +939,20 @@ func TestTryAggregateProofs(t *testing.T) {
 			},
 		},
 		{
-			name: "time to send final, state error ok",
+			name: "healthy pair above a quarantined pair is aggregated",
 			setup: func(m mox, a *Aggregator) {
-				a.cfg.VerifyProofInterval = configTypes.NewDuration(1)
 				m.proverMock.On("Name").Return(proverName).Times(3)
 				m.proverMock.On("ID").Return(proverID).Times(3)
 				m.proverMock.On("Addr").Return("addr")
 				dbTx := &mocks.DbTxMock{}
 				m.stateMock.On("BeginStateTransaction", mock.MatchedBy(matchProverCtxFn)).Return(dbTx, nil).Twice()
 				dbTx.On("Commit", mock.MatchedBy(matchProverCtxFn)).Return(nil).Twice()
-				m.stateMock.On("GetProofsToAggregate", mock.MatchedBy(matchProverCtxFn), nil).Return(&proof1, &proof2, nil).Once()
+				quarantinedProof1 := state.Proof{Proof: "quarantinedProof1", BatchNumber: 10, BatchNumberFinal: 15}
+				quarantinedProof2 := state.Proof{Proof: "quarantinedProof2", BatchNumber: 16, BatchNumberFinal: 22}
+				a.aggrQuarantine = newAggregationQuarantine(1)
+				a.aggrQuarantine.recordFailure(quarantinedProof1.BatchNumber, quarantinedProof2.BatchNumberFinal)
+				m.stateMock.On("GetProofsToAggregate", mock.MatchedBy(matchProverCtxFn), uint64(0), cfg.MaxBatchesPerFinalProof, nil).Return(&quarantinedProof1, &quarantinedProof2, nil).Once()
+				m.stateMock.On("GetProofsToAggregate", mock.MatchedBy(matchProverCtxFn), quarantinedProof1.BatchNumber+1, cfg.MaxBatchesPerFinalProof, nil).Return(&proof1, &proof2, nil).Once()
 				m.stateMock.
 					On("UpdateGeneratedProof", mock.MatchedBy(matchProverCtxFn), &proof1, dbTx).
 					Run(func(args mock.Arguments) {
@@ -653,12 +988,77 @@ func TestTryAggregateProofs(t *testing.T) {
 						assert.InDelta(time.Now().Unix(), proof.GeneratingSince.Unix(), float64(time.Second))
 					},
 				).Return(nil).Once()
-				isSyncedCall := m.stateMock.
-					On("GetLastVerifiedBatch", mock.MatchedBy(matchProverCtxFn), nil).
-					Return(&state.VerifiedBatch{BatchNumber: uint64(42)}, nil).Once()
-				m.etherman.On("GetLatestVerifiedBatchNum").Return(uint64(42), nil).Once()
-				// make tryBuildFinalProof fail ASAP
-				m.stateMock.On("GetLastVerifiedBatch", mock.MatchedBy(matchProverCtxFn), nil).Return(nil, errBanana).Once().NotBefore(isSyncedCall)
+				m.stateMock.On("UpdateGeneratedProof", mock.MatchedBy(matchAggregatorCtxFn), mock.Anything, nil).Run(
+					func(args mock.Arguments) {
+						proof := args[1].(*state.Proof)
+						assert.Equal(proof1.BatchNumber, proof.BatchNumber)
+						assert.Equal(proof2.BatchNumberFinal, proof.BatchNumberFinal)
+						assert.Equal(&proverName, proof.Prover)
+						assert.Equal(&proverID, proof.ProverID)
+						assert.Equal(string(b), proof.InputProver)
+						assert.Equal(recursiveProof, proof.Proof)
+						assert.Nil(proof.GeneratingSince)
+					},
+				).Return(nil).Once()
+			},
+			asserts: func(result bool, a *Aggregator, err error) {
+				assert.True(result)
+				assert.NoError(err)
+				assert.True(a.aggrQuarantine.isQuarantined(10, 22))
+			},
+		},
+		{
+			name: "time to send final, state error ok",
+			setup: func(m mox, a *Aggregator) {
+				a.cfg.VerifyProofInterval = configTypes.NewDuration(1)
+				m.proverMock.On("Name").Return(proverName).Times(3)
+				m.proverMock.On("ID").Return(proverID).Times(3)
+				m.proverMock.On("Addr").Return("addr")
+				dbTx := &mocks.DbTxMock{}
+				m.stateMock.On("BeginStateTransaction", mock.MatchedBy(matchProverCtxFn)).Return(dbTx, nil).Twice()
+				dbTx.On("Commit", mock.MatchedBy(matchProverCtxFn)).Return(nil).Twice()
+				m.stateMock.On("GetProofsToAggregate", mock.MatchedBy(matchProverCtxFn), uint64(0), cfg.MaxBatchesPerFinalProof, nil).Return(&proof1, &proof2, nil).Once()
+				m.stateMock.
+					On("UpdateGeneratedProof", mock.MatchedBy(matchProverCtxFn), &proof1, dbTx).
+					Run(func(args mock.Arguments) {
+						assert.NotNil(args[1].(*state.Proof).GeneratingSince)
+					}).
+					Return(nil).
+					Once()
+				m.stateMock.
+					On("UpdateGeneratedProof", mock.MatchedBy(matchProverCtxFn), &proof2, dbTx).
+					Run(func(args mock.Arguments) {
+						assert.NotNil(args[1].(*state.Proof).GeneratingSince)
+					}).
+					Return(nil).
+					Once()
+				m.proverMock.On("AggregatedProof", proof1.Proof, proof2.Proof).Return(&proofID, nil).Once()
+				m.proverMock.On("WaitRecursiveProof", mock.MatchedBy(matchProverCtxFn), proofID).Return(recursiveProof, nil).Once()
+				m.stateMock.On("DeleteGeneratedProofs", mock.MatchedBy(matchProverCtxFn), proof1.BatchNumber, proof2.BatchNumberFinal, dbTx).Return(nil).Once()
+				expectedInputProver := map[string]interface{}{
+					"recursive_proof_1": proof1.Proof,
+					"recursive_proof_2": proof2.Proof,
+				}
+				b, err := json.Marshal(expectedInputProver)
+				require.NoError(err)
+				m.stateMock.On("AddGeneratedProof", mock.MatchedBy(matchProverCtxFn), mock.Anything, dbTx).Run(
+					func(args mock.Arguments) {
+						proof := args[1].(*state.Proof)
+						assert.Equal(proof1.BatchNumber, proof.BatchNumber)
+						assert.Equal(proof2.BatchNumberFinal, proof.BatchNumberFinal)
+						assert.Equal(&proverName, proof.Prover)
+						assert.Equal(&proverID, proof.ProverID)
+						assert.Equal(string(b), proof.InputProver)
+						assert.Equal(recursiveProof, proof.Proof)
+						assert.InDelta(time.Now().Unix(), proof.GeneratingSince.Unix(), float64(time.Second))
+					},
+				).Return(nil).Once()
+				isSyncedCall := m.stateMock.
+					On("GetLastVerifiedBatch", mock.MatchedBy(matchProverCtxFn), nil).
+					Return(&state.VerifiedBatch{BatchNumber: uint64(42)}, nil).Once()
+				m.etherman.On("GetLatestVerifiedBatchNum").Return(uint64(42), nil).Once()
+				// make tryBuildFinalProof fail ASAP
+				m.stateMock.On("GetLastVerifiedBatch", mock.MatchedBy(matchProverCtxFn), nil).Return(nil, errBanana).Once().NotBefore(isSyncedCall)
 				m.stateMock.On("UpdateGeneratedProof", mock.MatchedBy(matchAggregatorCtxFn), mock.Anything, nil).Run(
 					func(args mock.Arguments) {
 						proof := args[1].(*state.Proof)
@@ -709,15 +1109,52 @@ func TestTryAggregateProofs(t *testing.T) {
 	}
 }
 
+func TestUnlockProofsToAggregateRetry(t *testing.T) {
+	errBanana := errors.New("banana")
+	now := time.Now()
+	proof1 := state.Proof{BatchNumber: 23, BatchNumberFinal: 30, GeneratingSince: &now}
+	proof2 := state.Proof{BatchNumber: 31, BatchNumberFinal: 42, GeneratingSince: &now}
+	stateMock := mocks.NewStateMock(t)
+	cfg := newTestConfig()
+	cfg.UnlockProofsMaxRetries = 2
+	a, err := New(cfg, stateMock, mocks.NewEthTxManager(t), mocks.NewEtherman(t))
+	require.NoError(t, err)
+	clk := newFakeClock(now)
+	a.clock = clk
+	dbTx := &mocks.DbTxMock{}
+	stateMock.On("BeginStateTransaction", mock.Anything).Return(nil, errBanana).Once()
+	stateMock.On("BeginStateTransaction", mock.Anything).Return(dbTx, nil).Once()
+	stateMock.On("UpdateGeneratedProof", mock.Anything, &proof1, dbTx).Return(nil).Once()
+	stateMock.On("UpdateGeneratedProof", mock.Anything, &proof2, dbTx).Return(nil).Once()
+	dbTx.On("Commit", mock.Anything).Return(nil).Once()
+
+	done := make(chan error)
+	go func() {
+		done <- a.unlockProofsToAggregate(context.Background(), &proof1, &proof2)
+	}()
+
+	for unlocked := false; !unlocked; {
+		select {
+		case err = <-done:
+			unlocked = true
+		case <-time.After(time.Millisecond):
+			clk.Advance(unlockProofsRetryInterval)
+		}
+	}
+
+	require.NoError(t, err)
+	assert.Nil(t, proof1.GeneratingSince)
+	assert.Nil(t, proof2.GeneratingSince)
+	dbTx.AssertExpectations(t)
+}
+
 func TestTryGenerateBatchProof(t *testing.T) {
 	require := require.New(t)
 	assert := assert.New(t)
 	from := common.BytesToAddress([]byte("from"))
-	cfg := Config{
-		VerifyProofInterval:        configTypes.NewDuration(10000000),
-		TxProfitabilityCheckerType: ProfitabilityAcceptAll,
-		SenderAddress:              from.Hex(),
-	}
+	cfg := newTestConfig()
+	cfg.VerifyProofInterval = configTypes.NewDuration(10000000)
+	cfg.SenderAddress = from.Hex()
 	lastVerifiedBatchNum := uint64(22)
 	batchNum := uint64(23)
 	lastVerifiedBatch := state.VerifiedBatch{
@@ -732,7 +1169,7 @@ func TestTryGenerateBatchProof(t *testing.T) {
 	proofID := "proofId"
 	proverName := "proverName"
 	proverID := "proverID"
-	recursiveProof := "recursiveProof"
+	recursiveProof := `{"proof":"recursiveProof"}`
 	errBanana := errors.New("banana")
 	proverCtx := context.WithValue(context.Background(), "owner", "prover") //nolint:staticcheck
 	matchProverCtxFn := func(ctx context.Context) bool { return ctx.Value("owner") == "prover" }
@@ -768,6 +1205,56 @@ func TestTryGenerateBatchProof(t *testing.T) {
 				assert.NoError(err)
 			},
 		},
+		{
+			name: "batch already claimed by another prover",
+			setup: func(m mox, a *Aggregator) {
+				m.proverMock.On("Name").Return(proverName).Twice()
+				m.proverMock.On("ID").Return(proverID).Twice()
+				m.proverMock.On("Addr").Return("addr")
+				m.stateMock.On("GetLastVerifiedBatch", mock.MatchedBy(matchProverCtxFn), nil).Return(&lastVerifiedBatch, nil).Once()
+				m.stateMock.On("GetVirtualBatchToProve", mock.MatchedBy(matchProverCtxFn), lastVerifiedBatchNum, nil).Return(&batchToProve, nil).Once()
+				m.stateMock.On("AddGeneratedProof", mock.MatchedBy(matchProverCtxFn), mock.Anything, nil).Return(state.ErrProofAlreadyClaimed).Once()
+			},
+			asserts: func(result bool, a *Aggregator, err error) {
+				assert.False(result)
+				assert.NoError(err)
+			},
+		},
+		{
+			name: "previous batch not synchronized",
+			setup: func(m mox, a *Aggregator) {
+				m.proverMock.On("Name").Return(proverName).Twice()
+				m.proverMock.On("ID").Return(proverID).Twice()
+				m.proverMock.On("Addr").Return("addr")
+				m.stateMock.On("GetLastVerifiedBatch", mock.MatchedBy(matchProverCtxFn), nil).Return(&lastVerifiedBatch, nil).Once()
+				m.stateMock.On("GetVirtualBatchToProve", mock.MatchedBy(matchProverCtxFn), lastVerifiedBatchNum, nil).Return(&batchToProve, nil).Once()
+				m.stateMock.On("AddGeneratedProof", mock.MatchedBy(matchProverCtxFn), mock.Anything, nil).Return(nil).Once()
+				m.stateMock.On("GetBatchByNumber", mock.MatchedBy(matchProverCtxFn), lastVerifiedBatchNum, nil).Return(nil, state.ErrStateNotSynchronized).Once()
+				m.stateMock.On("DeleteGeneratedProofs", mock.MatchedBy(matchAggregatorCtxFn), batchToProve.BatchNumber, batchToProve.BatchNumber, nil).Return(nil).Once()
+			},
+			asserts: func(result bool, a *Aggregator, err error) {
+				assert.False(result)
+				assert.ErrorIs(err, state.ErrStateNotSynchronized)
+			},
+		},
+		{
+			name: "sequenced accumulated input hash mismatch",
+			setup: func(m mox, a *Aggregator) {
+				a.cfg.ValidateSequencedAccInputHash = true
+				m.proverMock.On("Name").Return(proverName).Twice()
+				m.proverMock.On("ID").Return(proverID).Twice()
+				m.proverMock.On("Addr").Return("addr")
+				m.stateMock.On("GetLastVerifiedBatch", mock.MatchedBy(matchProverCtxFn), nil).Return(&lastVerifiedBatch, nil).Once()
+				m.stateMock.On("GetVirtualBatchToProve", mock.MatchedBy(matchProverCtxFn), lastVerifiedBatchNum, nil).Return(&batchToProve, nil).Once()
+				m.stateMock.On("AddGeneratedProof", mock.MatchedBy(matchProverCtxFn), mock.Anything, nil).Return(nil).Once()
+				m.etherman.On("GetSequencedBatchAccInputHash", mock.MatchedBy(matchProverCtxFn), batchToProve.BatchNumber).Return(common.HexToHash("0x1"), nil).Once()
+				m.stateMock.On("DeleteGeneratedProofs", mock.MatchedBy(matchAggregatorCtxFn), batchToProve.BatchNumber, batchToProve.BatchNumber, nil).Return(nil).Once()
+			},
+			asserts: func(result bool, a *Aggregator, err error) {
+				assert.False(result)
+				assert.ErrorIs(err, errAccInputHashMismatch)
+			},
+		},
 		{
 			name: "BatchProof prover error",
 			setup: func(m mox, a *Aggregator) {
@@ -827,6 +1314,66 @@ func TestTryGenerateBatchProof(t *testing.T) {
 				assert.ErrorIs(err, errBanana)
 			},
 		},
+		{
+			name: "WaitRecursiveProof invalid JSON proof",
+			setup: func(m mox, a *Aggregator) {
+				m.proverMock.On("Name").Return(proverName).Twice()
+				m.proverMock.On("ID").Return(proverID).Twice()
+				m.proverMock.On("Addr").Return("addr")
+				m.stateMock.On("GetLastVerifiedBatch", mock.MatchedBy(matchProverCtxFn), nil).Return(&lastVerifiedBatch, nil).Once()
+				m.stateMock.On("GetVirtualBatchToProve", mock.MatchedBy(matchProverCtxFn), lastVerifiedBatchNum, nil).Return(&batchToProve, nil).Once()
+				m.stateMock.On("AddGeneratedProof", mock.MatchedBy(matchProverCtxFn), mock.Anything, nil).Run(
+					func(args mock.Arguments) {
+						proof := args[1].(*state.Proof)
+						assert.Equal(batchToProve.BatchNumber, proof.BatchNumber)
+						assert.Equal(batchToProve.BatchNumber, proof.BatchNumberFinal)
+						assert.Equal(&proverName, proof.Prover)
+						assert.Equal(&proverID, proof.ProverID)
+						assert.InDelta(time.Now().Unix(), proof.GeneratingSince.Unix(), float64(time.Second))
+					},
+				).Return(nil).Once()
+				m.stateMock.On("GetBatchByNumber", mock.Anything, lastVerifiedBatchNum, nil).Return(&latestBatch, nil).Twice()
+				expectedInputProver, err := a.buildInputProver(context.Background(), &batchToProve)
+				require.NoError(err)
+				m.proverMock.On("BatchProof", expectedInputProver).Return(&proofID, nil).Once()
+				m.proverMock.On("WaitRecursiveProof", mock.MatchedBy(matchProverCtxFn), proofID).Return("invalid proof", nil).Once()
+				m.stateMock.On("DeleteGeneratedProofs", mock.MatchedBy(matchAggregatorCtxFn), batchToProve.BatchNumber, batchToProve.BatchNumber, nil).Return(nil).Once()
+			},
+			asserts: func(result bool, a *Aggregator, err error) {
+				assert.False(result)
+				assert.ErrorIs(err, errInvalidProof)
+			},
+		},
+		{
+			name: "WaitRecursiveProof empty proof",
+			setup: func(m mox, a *Aggregator) {
+				m.proverMock.On("Name").Return(proverName).Twice()
+				m.proverMock.On("ID").Return(proverID).Twice()
+				m.proverMock.On("Addr").Return("addr")
+				m.stateMock.On("GetLastVerifiedBatch", mock.MatchedBy(matchProverCtxFn), nil).Return(&lastVerifiedBatch, nil).Once()
+				m.stateMock.On("GetVirtualBatchToProve", mock.MatchedBy(matchProverCtxFn), lastVerifiedBatchNum, nil).Return(&batchToProve, nil).Once()
+				m.stateMock.On("AddGeneratedProof", mock.MatchedBy(matchProverCtxFn), mock.Anything, nil).Run(
+					func(args mock.Arguments) {
+						proof := args[1].(*state.Proof)
+						assert.Equal(batchToProve.BatchNumber, proof.BatchNumber)
+						assert.Equal(batchToProve.BatchNumber, proof.BatchNumberFinal)
+						assert.Equal(&proverName, proof.Prover)
+						assert.Equal(&proverID, proof.ProverID)
+						assert.InDelta(time.Now().Unix(), proof.GeneratingSince.Unix(), float64(time.Second))
+					},
+				).Return(nil).Once()
+				m.stateMock.On("GetBatchByNumber", mock.Anything, lastVerifiedBatchNum, nil).Return(&latestBatch, nil).Twice()
+				expectedInputProver, err := a.buildInputProver(context.Background(), &batchToProve)
+				require.NoError(err)
+				m.proverMock.On("BatchProof", expectedInputProver).Return(&proofID, nil).Once()
+				m.proverMock.On("WaitRecursiveProof", mock.MatchedBy(matchProverCtxFn), proofID).Return("", nil).Once()
+				m.stateMock.On("DeleteGeneratedProofs", mock.MatchedBy(matchAggregatorCtxFn), batchToProve.BatchNumber, batchToProve.BatchNumber, nil).Return(nil).Once()
+			},
+			asserts: func(result bool, a *Aggregator, err error) {
+				assert.False(result)
+				assert.ErrorIs(err, errEmptyProof)
+			},
+		},
 		{
 			name: "DeleteGeneratedProofs error after WaitRecursiveProof prover error",
 			setup: func(m mox, a *Aggregator) {
@@ -900,6 +1447,38 @@ func TestTryGenerateBatchProof(t *testing.T) {
 				assert.NoError(err)
 			},
 		},
+		{
+			name: "oversized input prover stores a reference",
+			setup: func(m mox, a *Aggregator) {
+				a.cfg.MaxInputProverBytes = 1
+				m.proverMock.On("Name").Return(proverName).Times(3)
+				m.proverMock.On("ID").Return(proverID).Times(3)
+				m.proverMock.On("Addr").Return("addr")
+				m.stateMock.On("GetLastVerifiedBatch", mock.MatchedBy(matchProverCtxFn), nil).Return(&lastVerifiedBatch, nil).Once()
+				m.stateMock.On("GetVirtualBatchToProve", mock.MatchedBy(matchProverCtxFn), lastVerifiedBatchNum, nil).Return(&batchToProve, nil).Once()
+				m.stateMock.On("AddGeneratedProof", mock.MatchedBy(matchProverCtxFn), mock.Anything, nil).Return(nil).Once()
+				m.stateMock.On("GetBatchByNumber", mock.Anything, lastVerifiedBatchNum, nil).Return(&latestBatch, nil).Twice()
+				expectedInputProver, err := a.buildInputProver(context.Background(), &batchToProve)
+				require.NoError(err)
+				// the whole input is still sent to the prover
+				m.proverMock.On("BatchProof", expectedInputProver).Return(&proofID, nil).Once()
+				m.proverMock.On("WaitRecursiveProof", mock.MatchedBy(matchProverCtxFn), proofID).Return(recursiveProof, nil).Once()
+				b, err := json.Marshal(expectedInputProver)
+				require.NoError(err)
+				expectedRef := fmt.Sprintf(`{"omitted_input_prover_batch_num":%d,"omitted_input_prover_bytes":%d}`, batchNum, len(b))
+				m.stateMock.On("UpdateGeneratedProof", mock.MatchedBy(matchAggregatorCtxFn), mock.Anything, nil).Run(
+					func(args mock.Arguments) {
+						proof := args[1].(*state.Proof)
+						assert.Equal(expectedRef, proof.InputProver)
+						assert.Equal(recursiveProof, proof.Proof)
+					},
+				).Return(nil).Once()
+			},
+			asserts: func(result bool, a *Aggregator, err error) {
+				assert.True(result)
+				assert.NoError(err)
+			},
+		},
 		{
 			name: "time to send final, state error ok",
 			setup: func(m mox, a *Aggregator) {
@@ -987,11 +1566,9 @@ func TestTryBuildFinalProof(t *testing.T) {
 	assert := assert.New(t)
 	errBanana := errors.New("banana")
 	from := common.BytesToAddress([]byte("from"))
-	cfg := Config{
-		VerifyProofInterval:        configTypes.NewDuration(10000000),
-		TxProfitabilityCheckerType: ProfitabilityAcceptAll,
-		SenderAddress:              from.Hex(),
-	}
+	cfg := newTestConfig()
+	cfg.VerifyProofInterval = configTypes.NewDuration(10000000)
+	cfg.SenderAddress = from.Hex()
 	latestVerifiedBatchNum := uint64(22)
 	batchNum := uint64(23)
 	batchNumFinal := uint64(42)
@@ -1019,6 +1596,18 @@ func TestTryBuildFinalProof(t *testing.T) {
 		BatchNumber:      uint64(123),
 		BatchNumberFinal: uint64(456),
 	}
+	staleProof := state.Proof{
+		ProofID:          &proofID,
+		Proof:            proof,
+		BatchNumber:      latestVerifiedBatchNum - 2,
+		BatchNumberFinal: batchNumFinal,
+	}
+	belowProof := state.Proof{
+		ProofID:          &proofID,
+		Proof:            proof,
+		BatchNumber:      latestVerifiedBatchNum - 2,
+		BatchNumberFinal: latestVerifiedBatchNum,
+	}
 	verifiedBatch := state.VerifiedBatch{
 		BatchNumber: latestVerifiedBatchNum,
 	}
@@ -1060,29 +1649,66 @@ func TestTryBuildFinalProof(t *testing.T) {
 			},
 		},
 		{
-			name: "nil proof, error requesting the proof triggers defer",
+			name: "sender balance below the minimum doesn't lock a proof",
+			setup: func(m mox, a *Aggregator) {
+				a.cfg.MinSenderBalance = TokenAmountWithDecimals{big.NewInt(1000)}
+				m.proverMock.On("Name").Return(proverName).Once()
+				m.proverMock.On("ID").Return(proverID).Once()
+				m.proverMock.On("Addr").Return("addr").Once()
+				m.stateMock.On("GetLastVerifiedBatch", mock.MatchedBy(matchProverCtxFn), nil).Return(&verifiedBatch, nil).Once()
+				m.etherman.On("GetLatestVerifiedBatchNum").Return(latestVerifiedBatchNum, nil).Once()
+				m.etherman.On("GetBalance", mock.MatchedBy(matchProverCtxFn), from).Return(big.NewInt(999), nil).Once()
+			},
+			asserts: func(result bool, a *Aggregator, err error) {
+				assert.False(result)
+				assert.NoError(err)
+				assert.Equal(int32(1), atomic.LoadInt32(&a.lowSenderBalance))
+			},
+		},
+		{
+			name:  "sender balance error",
+			proof: &proofToVerify,
+			setup: func(m mox, a *Aggregator) {
+				a.cfg.MinSenderBalance = TokenAmountWithDecimals{big.NewInt(1000)}
+				m.proverMock.On("Name").Return(proverName).Once()
+				m.proverMock.On("ID").Return(proverID).Once()
+				m.proverMock.On("Addr").Return("addr").Once()
+				m.stateMock.On("GetLastVerifiedBatch", mock.MatchedBy(matchProverCtxFn), nil).Return(&verifiedBatch, nil).Once()
+				m.etherman.On("GetLatestVerifiedBatchNum").Return(latestVerifiedBatchNum, nil).Once()
+				m.etherman.On("GetBalance", mock.MatchedBy(matchProverCtxFn), from).Return(nil, errBanana).Once()
+			},
+			asserts: func(result bool, a *Aggregator, err error) {
+				assert.False(result)
+				assert.ErrorIs(err, errBanana)
+			},
+		},
+		{
+			name:  "sender balance above the minimum",
+			proof: &proofToVerify,
 			setup: func(m mox, a *Aggregator) {
+				a.cfg.MinSenderBalance = TokenAmountWithDecimals{big.NewInt(1000)}
+				a.lowSenderBalance = 1
 				m.proverMock.On("Name").Return(proverName).Twice()
 				m.proverMock.On("ID").Return(proverID).Twice()
-				m.proverMock.On("Addr").Return("addr").Twice()
+				m.proverMock.On("Addr").Return(proverID).Twice()
+				m.etherman.On("GetBalance", mock.MatchedBy(matchProverCtxFn), from).Return(big.NewInt(1000), nil).Once()
 				m.stateMock.On("GetLastVerifiedBatch", mock.MatchedBy(matchProverCtxFn), nil).Return(&verifiedBatch, nil).Twice()
 				m.etherman.On("GetLatestVerifiedBatchNum").Return(latestVerifiedBatchNum, nil).Once()
-				m.stateMock.On("GetProofReadyToVerify", mock.MatchedBy(matchProverCtxFn), latestVerifiedBatchNum, nil).Return(&proofToVerify, nil).Once()
-				proofGeneratingTrueCall := m.stateMock.On("UpdateGeneratedProof", mock.MatchedBy(matchProverCtxFn), &proofToVerify, nil).Return(nil).Once()
-				m.proverMock.On("FinalProof", proofToVerify.Proof, from.String()).Return(nil, errBanana).Once()
-				m.stateMock.
-					On("UpdateGeneratedProof", mock.MatchedBy(matchAggregatorCtxFn), &proofToVerify, nil).
-					Return(nil).
-					Once().
-					NotBefore(proofGeneratingTrueCall)
+				m.stateMock.On("CheckProofContainsCompleteSequences", mock.MatchedBy(matchProverCtxFn), &proofToVerify, nil).Return(true, nil).Once()
+				m.stateMock.On("GetLastVirtualBatchNum", mock.MatchedBy(matchProverCtxFn), nil).Return(batchNumFinal, nil).Once()
+				m.proverMock.On("FinalProof", proofToVerify.Proof, from.String()).Return(&finalProofID, nil).Once()
+				m.proverMock.On("WaitFinalProof", mock.MatchedBy(matchProverCtxFn), finalProofID).Return(&finalProof, nil).Once()
 			},
 			asserts: func(result bool, a *Aggregator, err error) {
-				assert.False(result)
-				assert.ErrorIs(err, errBanana)
+				assert.True(result)
+				assert.NoError(err)
+			},
+			assertFinalMsg: func(msg *finalProofMsg) {
+				assert.Equal(&proofToVerify, msg.recursiveProof)
 			},
 		},
 		{
-			name: "nil proof, error building the proof triggers defer",
+			name: "nil proof, error requesting the proof triggers defer",
 			setup: func(m mox, a *Aggregator) {
 				m.proverMock.On("Name").Return(proverName).Twice()
 				m.proverMock.On("ID").Return(proverID).Twice()
@@ -1091,8 +1717,8 @@ func TestTryBuildFinalProof(t *testing.T) {
 				m.etherman.On("GetLatestVerifiedBatchNum").Return(latestVerifiedBatchNum, nil).Once()
 				m.stateMock.On("GetProofReadyToVerify", mock.MatchedBy(matchProverCtxFn), latestVerifiedBatchNum, nil).Return(&proofToVerify, nil).Once()
 				proofGeneratingTrueCall := m.stateMock.On("UpdateGeneratedProof", mock.MatchedBy(matchProverCtxFn), &proofToVerify, nil).Return(nil).Once()
-				m.proverMock.On("FinalProof", proofToVerify.Proof, from.String()).Return(&finalProofID, nil).Once()
-				m.proverMock.On("WaitFinalProof", mock.MatchedBy(matchProverCtxFn), finalProofID).Return(nil, errBanana).Once()
+				m.stateMock.On("GetLastVirtualBatchNum", mock.MatchedBy(matchProverCtxFn), nil).Return(batchNumFinal, nil).Once()
+				m.proverMock.On("FinalProof", proofToVerify.Proof, from.String()).Return(nil, errBanana).Once()
 				m.stateMock.
 					On("UpdateGeneratedProof", mock.MatchedBy(matchAggregatorCtxFn), &proofToVerify, nil).
 					Return(nil).
@@ -1105,7 +1731,70 @@ func TestTryBuildFinalProof(t *testing.T) {
 			},
 		},
 		{
-			name: "nil proof, generic error from GetProofReadyToVerify",
+			name: "nil proof, final batch not synced triggers defer",
+			setup: func(m mox, a *Aggregator) {
+				m.proverMock.On("Name").Return(proverName).Once()
+				m.proverMock.On("ID").Return(proverID).Once()
+				m.proverMock.On("Addr").Return("addr").Once()
+				m.stateMock.On("GetLastVerifiedBatch", mock.MatchedBy(matchProverCtxFn), nil).Return(&verifiedBatch, nil).Twice()
+				m.etherman.On("GetLatestVerifiedBatchNum").Return(latestVerifiedBatchNum, nil).Once()
+				m.stateMock.On("GetProofReadyToVerify", mock.MatchedBy(matchProverCtxFn), latestVerifiedBatchNum, nil).Return(&proofToVerify, nil).Once()
+				proofGeneratingTrueCall := m.stateMock.On("UpdateGeneratedProof", mock.MatchedBy(matchProverCtxFn), &proofToVerify, nil).Return(nil).Once()
+				m.stateMock.On("GetLastVirtualBatchNum", mock.MatchedBy(matchProverCtxFn), nil).Return(batchNumFinal-1, nil).Once()
+				m.stateMock.
+					On("UpdateGeneratedProof", mock.MatchedBy(matchAggregatorCtxFn), &proofToVerify, nil).
+					Return(nil).
+					Once().
+					NotBefore(proofGeneratingTrueCall)
+			},
+			asserts: func(result bool, a *Aggregator, err error) {
+				assert.False(result)
+				assert.NoError(err)
+			},
+		},
+		{
+			name:  "proof with final batch not synced deferred",
+			proof: &proofToVerify,
+			setup: func(m mox, a *Aggregator) {
+				m.proverMock.On("Name").Return(proverName).Once()
+				m.proverMock.On("ID").Return(proverID).Once()
+				m.proverMock.On("Addr").Return(proverID).Once()
+				m.stateMock.On("GetLastVerifiedBatch", mock.MatchedBy(matchProverCtxFn), nil).Return(&verifiedBatch, nil).Twice()
+				m.etherman.On("GetLatestVerifiedBatchNum").Return(latestVerifiedBatchNum, nil).Once()
+				m.stateMock.On("CheckProofContainsCompleteSequences", mock.MatchedBy(matchProverCtxFn), &proofToVerify, nil).Return(true, nil).Once()
+				m.stateMock.On("GetLastVirtualBatchNum", mock.MatchedBy(matchProverCtxFn), nil).Return(batchNumFinal-1, nil).Once()
+			},
+			asserts: func(result bool, a *Aggregator, err error) {
+				assert.False(result)
+				assert.NoError(err)
+			},
+		},
+		{
+			name: "nil proof, error building the proof triggers defer",
+			setup: func(m mox, a *Aggregator) {
+				m.proverMock.On("Name").Return(proverName).Twice()
+				m.proverMock.On("ID").Return(proverID).Twice()
+				m.proverMock.On("Addr").Return("addr").Twice()
+				m.stateMock.On("GetLastVerifiedBatch", mock.MatchedBy(matchProverCtxFn), nil).Return(&verifiedBatch, nil).Twice()
+				m.etherman.On("GetLatestVerifiedBatchNum").Return(latestVerifiedBatchNum, nil).Once()
+				m.stateMock.On("GetProofReadyToVerify", mock.MatchedBy(matchProverCtxFn), latestVerifiedBatchNum, nil).Return(&proofToVerify, nil).Once()
+				proofGeneratingTrueCall := m.stateMock.On("UpdateGeneratedProof", mock.MatchedBy(matchProverCtxFn), &proofToVerify, nil).Return(nil).Once()
+				m.stateMock.On("GetLastVirtualBatchNum", mock.MatchedBy(matchProverCtxFn), nil).Return(batchNumFinal, nil).Once()
+				m.proverMock.On("FinalProof", proofToVerify.Proof, from.String()).Return(&finalProofID, nil).Once()
+				m.proverMock.On("WaitFinalProof", mock.MatchedBy(matchProverCtxFn), finalProofID).Return(nil, errBanana).Once()
+				m.stateMock.
+					On("UpdateGeneratedProof", mock.MatchedBy(matchAggregatorCtxFn), &proofToVerify, nil).
+					Return(nil).
+					Once().
+					NotBefore(proofGeneratingTrueCall)
+			},
+			asserts: func(result bool, a *Aggregator, err error) {
+				assert.False(result)
+				assert.ErrorIs(err, errBanana)
+			},
+		},
+		{
+			name: "nil proof, generic error from GetProofReadyToVerify",
 			setup: func(m mox, a *Aggregator) {
 				m.proverMock.On("Name").Return(proverName).Once()
 				m.proverMock.On("ID").Return(proverID).Once()
@@ -1144,6 +1833,7 @@ func TestTryBuildFinalProof(t *testing.T) {
 				m.etherman.On("GetLatestVerifiedBatchNum").Return(latestVerifiedBatchNum, nil).Once()
 				m.stateMock.On("GetProofReadyToVerify", mock.MatchedBy(matchProverCtxFn), latestVerifiedBatchNum, nil).Return(&proofToVerify, nil).Once()
 				m.stateMock.On("UpdateGeneratedProof", mock.MatchedBy(matchProverCtxFn), &proofToVerify, nil).Return(nil).Once()
+				m.stateMock.On("GetLastVirtualBatchNum", mock.MatchedBy(matchProverCtxFn), nil).Return(batchNumFinal, nil).Once()
 				m.proverMock.On("FinalProof", proofToVerify.Proof, from.String()).Return(&finalProofID, nil).Once()
 				m.proverMock.On("WaitFinalProof", mock.MatchedBy(matchProverCtxFn), finalProofID).Return(&finalProof, nil).Once()
 			},
@@ -1188,6 +1878,61 @@ func TestTryBuildFinalProof(t *testing.T) {
 				assert.NoError(err)
 			},
 		},
+		{
+			name:  "proof overlapping the next batch to verify discarded",
+			proof: &staleProof,
+			setup: func(m mox, a *Aggregator) {
+				a.cfg.DiscardOverlappingFinalProofs = true
+				m.proverMock.On("Name").Return(proverName).Once()
+				m.proverMock.On("ID").Return(proverID).Once()
+				m.proverMock.On("Addr").Return(proverID).Once()
+				m.stateMock.On("GetLastVerifiedBatch", mock.MatchedBy(matchProverCtxFn), nil).Return(&verifiedBatch, nil).Twice()
+				m.etherman.On("GetLatestVerifiedBatchNum").Return(latestVerifiedBatchNum, nil).Once()
+				m.stateMock.On("DeleteGeneratedProofs", mock.MatchedBy(matchProverCtxFn), staleProof.BatchNumber, staleProof.BatchNumberFinal, nil).Return(nil).Once()
+			},
+			asserts: func(result bool, a *Aggregator, err error) {
+				assert.False(result)
+				assert.NoError(err)
+			},
+		},
+		{
+			name:  "proof below the next batch to verify deleted",
+			proof: &belowProof,
+			setup: func(m mox, a *Aggregator) {
+				m.proverMock.On("Name").Return(proverName).Once()
+				m.proverMock.On("ID").Return(proverID).Once()
+				m.proverMock.On("Addr").Return(proverID).Once()
+				m.stateMock.On("GetLastVerifiedBatch", mock.MatchedBy(matchProverCtxFn), nil).Return(&verifiedBatch, nil).Twice()
+				m.etherman.On("GetLatestVerifiedBatchNum").Return(latestVerifiedBatchNum, nil).Once()
+				m.stateMock.On("DeleteGeneratedProofs", mock.MatchedBy(matchProverCtxFn), belowProof.BatchNumber, belowProof.BatchNumberFinal, nil).Return(nil).Once()
+			},
+			asserts: func(result bool, a *Aggregator, err error) {
+				assert.False(result)
+				assert.NoError(err)
+			},
+		},
+		{
+			name:  "proof overlapping the next batch to verify accepted",
+			proof: &staleProof,
+			setup: func(m mox, a *Aggregator) {
+				m.proverMock.On("Name").Return(proverName).Twice()
+				m.proverMock.On("ID").Return(proverID).Twice()
+				m.proverMock.On("Addr").Return(proverID).Twice()
+				m.stateMock.On("GetLastVerifiedBatch", mock.MatchedBy(matchProverCtxFn), nil).Return(&verifiedBatch, nil).Twice()
+				m.etherman.On("GetLatestVerifiedBatchNum").Return(latestVerifiedBatchNum, nil).Once()
+				m.stateMock.On("CheckProofContainsCompleteSequences", mock.MatchedBy(matchProverCtxFn), &staleProof, nil).Return(true, nil).Once()
+				m.stateMock.On("GetLastVirtualBatchNum", mock.MatchedBy(matchProverCtxFn), nil).Return(batchNumFinal, nil).Once()
+				m.proverMock.On("FinalProof", staleProof.Proof, from.String()).Return(&finalProofID, nil).Once()
+				m.proverMock.On("WaitFinalProof", mock.MatchedBy(matchProverCtxFn), finalProofID).Return(&finalProof, nil).Once()
+			},
+			asserts: func(result bool, a *Aggregator, err error) {
+				assert.True(result)
+				assert.NoError(err)
+			},
+			assertFinalMsg: func(msg *finalProofMsg) {
+				assert.Equal(&staleProof, msg.recursiveProof)
+			},
+		},
 		{
 			name:  "invalid proof (not a complete sequence) rejected",
 			proof: &proofToVerify,
@@ -1214,6 +1959,7 @@ func TestTryBuildFinalProof(t *testing.T) {
 				m.stateMock.On("GetLastVerifiedBatch", mock.MatchedBy(matchProverCtxFn), nil).Return(&verifiedBatch, nil).Twice()
 				m.etherman.On("GetLatestVerifiedBatchNum").Return(latestVerifiedBatchNum, nil).Once()
 				m.stateMock.On("CheckProofContainsCompleteSequences", mock.MatchedBy(matchProverCtxFn), &proofToVerify, nil).Return(true, nil).Once()
+				m.stateMock.On("GetLastVirtualBatchNum", mock.MatchedBy(matchProverCtxFn), nil).Return(batchNumFinal, nil).Once()
 				m.proverMock.On("FinalProof", proofToVerify.Proof, from.String()).Return(&finalProofID, nil).Once()
 				m.proverMock.On("WaitFinalProof", mock.MatchedBy(matchProverCtxFn), finalProofID).Return(&finalProof, nil).Once()
 			},
@@ -1227,6 +1973,61 @@ func TestTryBuildFinalProof(t *testing.T) {
 				assert.Equal(finalProof.Public.NewLocalExitRoot, msg.finalProof.Public.NewLocalExitRoot)
 			},
 		},
+		{
+			name:  "proof wider than max batches per final proof rejected",
+			proof: &proofToVerify,
+			setup: func(m mox, a *Aggregator) {
+				a.cfg.MaxBatchesPerFinalProof = batchNumFinal - batchNum
+				m.proverMock.On("Name").Return(proverName).Once()
+				m.proverMock.On("ID").Return(proverID).Once()
+				m.proverMock.On("Addr").Return(proverID).Once()
+				m.stateMock.On("GetLastVerifiedBatch", mock.MatchedBy(matchProverCtxFn), nil).Return(&verifiedBatch, nil).Twice()
+				m.etherman.On("GetLatestVerifiedBatchNum").Return(latestVerifiedBatchNum, nil).Once()
+			},
+			asserts: func(result bool, a *Aggregator, err error) {
+				assert.False(result)
+				assert.NoError(err)
+			},
+		},
+		{
+			name: "nil proof, proof ready to verify wider than max batches per final proof skipped",
+			setup: func(m mox, a *Aggregator) {
+				a.cfg.MaxBatchesPerFinalProof = batchNumFinal - batchNum
+				m.proverMock.On("Name").Return(proverName).Once()
+				m.proverMock.On("ID").Return(proverID).Once()
+				m.proverMock.On("Addr").Return(proverID).Once()
+				m.stateMock.On("GetLastVerifiedBatch", mock.MatchedBy(matchProverCtxFn), nil).Return(&verifiedBatch, nil).Twice()
+				m.etherman.On("GetLatestVerifiedBatchNum").Return(latestVerifiedBatchNum, nil).Once()
+				m.stateMock.On("GetProofReadyToVerify", mock.MatchedBy(matchProverCtxFn), latestVerifiedBatchNum, nil).Return(&proofToVerify, nil).Once()
+			},
+			asserts: func(result bool, a *Aggregator, err error) {
+				assert.False(result)
+				assert.NoError(err)
+			},
+		},
+		{
+			name:  "proof within max batches per final proof ok",
+			proof: &proofToVerify,
+			setup: func(m mox, a *Aggregator) {
+				a.cfg.MaxBatchesPerFinalProof = batchNumFinal - batchNum + 1
+				m.proverMock.On("Name").Return(proverName).Twice()
+				m.proverMock.On("ID").Return(proverID).Twice()
+				m.proverMock.On("Addr").Return(proverID).Twice()
+				m.stateMock.On("GetLastVerifiedBatch", mock.MatchedBy(matchProverCtxFn), nil).Return(&verifiedBatch, nil).Twice()
+				m.etherman.On("GetLatestVerifiedBatchNum").Return(latestVerifiedBatchNum, nil).Once()
+				m.stateMock.On("CheckProofContainsCompleteSequences", mock.MatchedBy(matchProverCtxFn), &proofToVerify, nil).Return(true, nil).Once()
+				m.stateMock.On("GetLastVirtualBatchNum", mock.MatchedBy(matchProverCtxFn), nil).Return(batchNumFinal, nil).Once()
+				m.proverMock.On("FinalProof", proofToVerify.Proof, from.String()).Return(&finalProofID, nil).Once()
+				m.proverMock.On("WaitFinalProof", mock.MatchedBy(matchProverCtxFn), finalProofID).Return(&finalProof, nil).Once()
+			},
+			asserts: func(result bool, a *Aggregator, err error) {
+				assert.True(result)
+				assert.NoError(err)
+			},
+			assertFinalMsg: func(msg *finalProofMsg) {
+				assert.Equal(finalProof.Proof, msg.finalProof.Proof)
+			},
+		},
 	}
 
 	for _, tc := range testCases {
@@ -1275,7 +2076,7 @@ func TestTryBuildFinalProof(t *testing.T) {
 func TestIsSynced(t *testing.T) {
 	require := require.New(t)
 	assert := assert.New(t)
-	cfg := Config{}
+	cfg := newTestConfig()
 	var nilBatchNum *uint64
 	batchNum := uint64(42)
 	errBanana := errors.New("banana")
@@ -1338,6 +2139,16 @@ func TestIsSynced(t *testing.T) {
 				m.etherman.On("GetLatestVerifiedBatchNum").Return(batchNum, nil).Once()
 			},
 		},
+		{
+			name:     "state ahead of L1",
+			synced:   false,
+			batchNum: nilBatchNum,
+			setup: func(m mox, a *Aggregator) {
+				latestVerifiedBatch := state.VerifiedBatch{BatchNumber: batchNum}
+				m.stateMock.On("GetLastVerifiedBatch", mock.Anything, nil).Return(&latestVerifiedBatch, nil).Once()
+				m.etherman.On("GetLatestVerifiedBatchNum").Return(batchNum-1, nil).Once()
+			},
+		},
 		{
 			name:     "ok with nil batch number",
 			synced:   true,
@@ -1385,3 +2196,1520 @@ func TestIsSynced(t *testing.T) {
 		})
 	}
 }
+
+// channelServerMock is a minimal prover stream used to exercise Channel. It
+// answers every request with the configured status response.
+type channelServerMock struct {
+	pb.AggregatorService_ChannelServer
+	ctx    context.Context
+	status *pb.GetStatusResponse
+
+	mu   sync.Mutex
+	sent []*pb.AggregatorMessage
+}
+
+func (s *channelServerMock) Context() context.Context { return s.ctx }
+
+func (s *channelServerMock) Send(msg *pb.AggregatorMessage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sent = append(s.sent, msg)
+	return nil
+}
+
+func (s *channelServerMock) Recv() (*pb.ProverMessage, error) {
+	return &pb.ProverMessage{
+		Response: &pb.ProverMessage_GetStatusResponse{GetStatusResponse: s.status},
+	}, nil
+}
+
+func (s *channelServerMock) sentRequests() []*pb.AggregatorMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*pb.AggregatorMessage{}, s.sent...)
+}
+
+func TestChannelMaxConnectedProvers(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+	maxProvers := 3
+	cfg := newTestConfig()
+	cfg.MaxConnectedProvers = maxProvers
+	a, err := New(cfg, mocks.NewStateMock(t), mocks.NewEthTxManager(t), mocks.NewEtherman(t))
+	require.NoError(err)
+	a.ctx, a.exit = context.WithCancel(context.Background())
+	defer a.exit()
+
+	for i := 0; i < maxProvers; i++ {
+		require.True(a.acquireProverSlot())
+	}
+
+	err = a.Channel(&channelServerMock{ctx: context.Background()})
+	require.Error(err)
+	assert.Equal(codes.ResourceExhausted, status.Code(err))
+	assert.Equal(int32(maxProvers), atomic.LoadInt32(&a.connectedProvers))
+
+	a.releaseProverSlot()
+	assert.True(a.acquireProverSlot())
+}
+
+func TestCleanupUngeneratedProofs(t *testing.T) {
+	errBanana := errors.New("banana")
+	testCases := []struct {
+		name    string
+		cleanup bool
+		setup   func(mox)
+		err     error
+	}{
+		{
+			name:    "cleanup disabled does not touch the state",
+			cleanup: false,
+		},
+		{
+			name:    "cleanup enabled deletes ungenerated proofs",
+			cleanup: true,
+			setup: func(m mox) {
+				m.stateMock.On("DeleteUngeneratedProofs", mock.Anything, nil).Return(nil).Once()
+			},
+		},
+		{
+			name:    "cleanup enabled returns state error",
+			cleanup: true,
+			setup: func(m mox) {
+				m.stateMock.On("DeleteUngeneratedProofs", mock.Anything, nil).Return(errBanana).Once()
+			},
+			err: errBanana,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			stateMock := mocks.NewStateMock(t)
+			cfg := newTestConfig()
+			cfg.CleanupUngeneratedOnStart = tc.cleanup
+			a, err := New(cfg, stateMock, mocks.NewEthTxManager(t), mocks.NewEtherman(t))
+			require.NoError(t, err)
+			if tc.setup != nil {
+				tc.setup(mox{stateMock: stateMock})
+			}
+
+			err = a.cleanupUngeneratedProofs(context.Background())
+
+			if tc.err != nil {
+				assert.ErrorIs(t, err, tc.err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestVerifyStoredProofs(t *testing.T) {
+	errBanana := errors.New("banana")
+	now := time.Now()
+	validProof := &state.Proof{BatchNumber: 1, BatchNumberFinal: 2, Proof: `{"proof":"valid"}`}
+	invalidProof := &state.Proof{BatchNumber: 3, BatchNumberFinal: 4, Proof: `{"proof":"trunc`}
+	generatingProof := &state.Proof{BatchNumber: 5, BatchNumberFinal: 5, GeneratingSince: &now}
+	testCases := []struct {
+		name   string
+		verify bool
+		setup  func(mox)
+		err    error
+	}{
+		{
+			name:   "verification disabled does not touch the state",
+			verify: false,
+		},
+		{
+			name:   "invalid proof is deleted and valid ones are kept",
+			verify: true,
+			setup: func(m mox) {
+				m.stateMock.On("ListProofs", mock.Anything, uint64(0), uint64(0), uint64(listProofsPageSize), nil).Return([]*state.Proof{validProof, invalidProof, generatingProof}, nil).Once()
+				m.stateMock.On("DeleteGeneratedProofs", mock.Anything, invalidProof.BatchNumber, invalidProof.BatchNumberFinal, nil).Return(nil).Once()
+			},
+		},
+		{
+			name:   "state error listing the proofs",
+			verify: true,
+			setup: func(m mox) {
+				m.stateMock.On("ListProofs", mock.Anything, uint64(0), uint64(0), uint64(listProofsPageSize), nil).Return(nil, errBanana).Once()
+			},
+			err: errBanana,
+		},
+		{
+			name:   "state error deleting an invalid proof",
+			verify: true,
+			setup: func(m mox) {
+				m.stateMock.On("ListProofs", mock.Anything, uint64(0), uint64(0), uint64(listProofsPageSize), nil).Return([]*state.Proof{invalidProof}, nil).Once()
+				m.stateMock.On("DeleteGeneratedProofs", mock.Anything, invalidProof.BatchNumber, invalidProof.BatchNumberFinal, nil).Return(errBanana).Once()
+			},
+			err: errBanana,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			stateMock := mocks.NewStateMock(t)
+			cfg := newTestConfig()
+			cfg.VerifyStoredProofsOnStartup = tc.verify
+			a, err := New(cfg, stateMock, mocks.NewEthTxManager(t), mocks.NewEtherman(t))
+			require.NoError(t, err)
+			if tc.setup != nil {
+				tc.setup(mox{stateMock: stateMock})
+			}
+
+			err = a.verifyStoredProofs(context.Background())
+
+			if tc.err != nil {
+				assert.ErrorIs(t, err, tc.err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestChannelProverWarmupDelay(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+	forkID := uint64(2)
+	cfg := newTestConfig()
+	cfg.ForkId = forkID
+	cfg.ProverWarmupDelay = configTypes.NewDuration(time.Minute)
+	// the state mock fails the test if any proof work is attempted
+	a, err := New(cfg, mocks.NewStateMock(t), mocks.NewEthTxManager(t), mocks.NewEtherman(t))
+	require.NoError(err)
+	a.ctx, a.exit = context.WithCancel(context.Background())
+	defer a.exit()
+	streamCtx, cancelStream := context.WithCancel(context.Background())
+	stream := &channelServerMock{
+		ctx: streamCtx,
+		status: &pb.GetStatusResponse{
+			ProverName: "proverName",
+			ProverId:   "proverID",
+			ForkId:     forkID,
+			Status:     pb.GetStatusResponse_STATUS_IDLE,
+		},
+	}
+	time.AfterFunc(100*time.Millisecond, cancelStream)
+
+	err = a.Channel(stream)
+
+	assert.ErrorIs(err, context.Canceled)
+	for _, req := range stream.sentRequests() {
+		assert.IsType(&pb.AggregatorMessage_GetStatusRequest{}, req.Request)
+	}
+}
+
+func TestTryGenerateBatchProofAttemptID(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+	logFile := filepath.Join(t.TempDir(), "aggregator.log")
+	log.Init(log.Config{Environment: log.EnvironmentProduction, Level: "debug", Outputs: []string{logFile}})
+	defer log.Init(log.Config{Environment: log.EnvironmentDevelopment, Level: "debug", Outputs: []string{"stderr"}})
+	cfg := newTestConfig()
+	cfg.VerifyProofInterval = configTypes.NewDuration(10000000)
+	lastVerifiedBatch := state.VerifiedBatch{BatchNumber: 22}
+	latestBatch := state.Batch{BatchNumber: 22}
+	batchToProve := state.Batch{BatchNumber: 23}
+	proofID := "proofId"
+	recursiveProof := `{"proof":"recursiveProof"}`
+	stateMock := mocks.NewStateMock(t)
+	proverMock := mocks.NewProverMock(t)
+	a, err := New(cfg, stateMock, mocks.NewEthTxManager(t), mocks.NewEtherman(t))
+	require.NoError(err)
+	a.ctx, a.exit = context.WithCancel(context.Background())
+	defer a.exit()
+	a.resetVerifyProofTime()
+	proverMock.On("Name").Return("proverName")
+	proverMock.On("ID").Return("proverID")
+	proverMock.On("Addr").Return("addr")
+	stateMock.On("GetLastVerifiedBatch", mock.Anything, nil).Return(&lastVerifiedBatch, nil).Once()
+	stateMock.On("GetVirtualBatchToProve", mock.Anything, lastVerifiedBatch.BatchNumber, nil).Return(&batchToProve, nil).Once()
+	stateMock.On("GetBatchByNumber", mock.Anything, lastVerifiedBatch.BatchNumber, nil).Return(&latestBatch, nil)
+	var addedAttemptID, updatedAttemptID *string
+	stateMock.On("AddGeneratedProof", mock.Anything, mock.Anything, nil).Run(func(args mock.Arguments) {
+		addedAttemptID = args[1].(*state.Proof).AttemptID
+	}).Return(nil).Once()
+	proverMock.On("BatchProof", mock.Anything).Return(&proofID, nil).Once()
+	proverMock.On("WaitRecursiveProof", mock.Anything, proofID).Return(recursiveProof, nil).Once()
+	stateMock.On("UpdateGeneratedProof", mock.Anything, mock.Anything, nil).Run(func(args mock.Arguments) {
+		updatedAttemptID = args[1].(*state.Proof).AttemptID
+	}).Return(nil).Once()
+
+	result, err := a.tryGenerateBatchProof(context.Background(), proverMock)
+
+	require.NoError(err)
+	assert.True(result)
+	require.NotNil(addedAttemptID)
+	require.NotNil(updatedAttemptID)
+	assert.Equal(*addedAttemptID, *updatedAttemptID)
+	logs, err := os.ReadFile(logFile)
+	require.NoError(err)
+	for _, line := range strings.Split(strings.TrimSpace(string(logs)), "\n") {
+		var entry map[string]interface{}
+		require.NoError(json.Unmarshal([]byte(line), &entry))
+		if strings.HasPrefix(entry["caller"].(string), "aggregator/aggregator.go") {
+			assert.Equal(*addedAttemptID, entry["attemptId"], entry["msg"])
+		}
+	}
+}
+
+func TestTryGenerateBatchProofFailureMetric(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+	zkevmMetrics.Init()
+	metrics.Register()
+	cfg := newTestConfig()
+	cfg.VerifyProofInterval = configTypes.NewDuration(10000000)
+	lastVerifiedBatch := state.VerifiedBatch{BatchNumber: 22}
+	latestBatch := state.Batch{BatchNumber: 22}
+	batchToProve := state.Batch{BatchNumber: 23}
+	proverName := "failingProverName"
+	stateMock := mocks.NewStateMock(t)
+	proverMock := mocks.NewProverMock(t)
+	a, err := New(cfg, stateMock, mocks.NewEthTxManager(t), mocks.NewEtherman(t))
+	require.NoError(err)
+	a.ctx, a.exit = context.WithCancel(context.Background())
+	defer a.exit()
+	proverMock.On("Name").Return(proverName)
+	proverMock.On("ID").Return("proverID")
+	proverMock.On("Addr").Return("addr")
+	stateMock.On("GetLastVerifiedBatch", mock.Anything, nil).Return(&lastVerifiedBatch, nil).Once()
+	stateMock.On("GetVirtualBatchToProve", mock.Anything, lastVerifiedBatch.BatchNumber, nil).Return(&batchToProve, nil).Once()
+	stateMock.On("GetBatchByNumber", mock.Anything, lastVerifiedBatch.BatchNumber, nil).Return(&latestBatch, nil)
+	stateMock.On("AddGeneratedProof", mock.Anything, mock.Anything, nil).Return(nil).Once()
+	proverMock.On("BatchProof", mock.Anything).Return(nil, errors.New("banana")).Once()
+	stateMock.On("DeleteGeneratedProofs", mock.Anything, batchToProve.BatchNumber, batchToProve.BatchNumber, nil).Return(nil).Once()
+	counterVec, ok := zkevmMetrics.CounterVec("aggregator_prover_proofs_total")
+	require.True(ok)
+	failures := counterVec.WithLabelValues(proverName, string(metrics.ProofResultLabelFailure))
+	successes := counterVec.WithLabelValues(proverName, string(metrics.ProofResultLabelSuccess))
+	failuresBefore := testutil.ToFloat64(failures)
+	successesBefore := testutil.ToFloat64(successes)
+
+	result, err := a.tryGenerateBatchProof(context.Background(), proverMock)
+
+	assert.Error(err)
+	assert.False(result)
+	assert.Equal(failuresBefore+1, testutil.ToFloat64(failures))
+	assert.Equal(successesBefore, testutil.ToFloat64(successes))
+}
+
+func TestBuildInputProver(t *testing.T) {
+	genesisBatch := state.Batch{BatchNumber: 0, StateRoot: common.HexToHash("0x0")}
+	previousBatch := state.Batch{BatchNumber: 22, StateRoot: common.HexToHash("0x22"), AccInputHash: common.HexToHash("0x2222")}
+	testCases := []struct {
+		name             string
+		batchNumber      uint64
+		setup            func(mox)
+		expectedPrevious *state.Batch
+		expectedErr      error
+	}{
+		{
+			name:        "genesis batch rejected",
+			batchNumber: 0,
+			expectedErr: errGenesisBatch,
+		},
+		{
+			name:        "first batch uses the genesis batch",
+			batchNumber: 1,
+			setup: func(m mox) {
+				m.stateMock.On("GetBatchByNumber", mock.Anything, uint64(0), nil).Return(&genesisBatch, nil).Once()
+			},
+			expectedPrevious: &genesisBatch,
+		},
+		{
+			name:        "batch uses the previous batch",
+			batchNumber: 23,
+			setup: func(m mox) {
+				m.stateMock.On("GetBatchByNumber", mock.Anything, uint64(22), nil).Return(&previousBatch, nil).Once()
+			},
+			expectedPrevious: &previousBatch,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			stateMock := mocks.NewStateMock(t)
+			a, err := New(newTestConfig(), stateMock, mocks.NewEthTxManager(t), mocks.NewEtherman(t))
+			require.NoError(t, err)
+			if tc.setup != nil {
+				tc.setup(mox{stateMock: stateMock})
+			}
+
+			inputProver, err := a.buildInputProver(context.Background(), &state.Batch{BatchNumber: tc.batchNumber})
+
+			if tc.expectedErr != nil {
+				assert.ErrorIs(t, err, tc.expectedErr)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, tc.expectedPrevious.BatchNumber, inputProver.PublicInputs.OldBatchNum)
+				assert.Equal(t, tc.expectedPrevious.StateRoot.Bytes(), inputProver.PublicInputs.OldStateRoot)
+				assert.Equal(t, tc.expectedPrevious.AccInputHash.Bytes(), inputProver.PublicInputs.OldAccInputHash)
+			}
+		})
+	}
+}
+
+func TestBuildInputProverBatchCache(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+	cfg := newTestConfig()
+	cfg.EnableBatchCache = true
+	stateMock := mocks.NewStateMock(t)
+	a, err := New(cfg, stateMock, mocks.NewEthTxManager(t), mocks.NewEtherman(t))
+	require.NoError(err)
+	newBatch := func(previous *state.Batch, batchL2Data string) *state.Batch {
+		batch := &state.Batch{
+			BatchNumber: previous.BatchNumber + 1,
+			StateRoot:   common.HexToHash(batchL2Data),
+			BatchL2Data: []byte(batchL2Data),
+			Timestamp:   time.Unix(1_000_000, 0),
+		}
+		batch.AccInputHash = accInputHash(previous.AccInputHash, batch)
+		return batch
+	}
+	batch22 := &state.Batch{BatchNumber: 22, StateRoot: common.HexToHash("0x22"), AccInputHash: common.HexToHash("0xa22")}
+	batch23 := newBatch(batch22, "0x23")
+	batch24 := newBatch(batch23, "0x24")
+	// only the previous batch of the first batch proved is read from the state
+	stateMock.On("GetBatchByNumber", mock.Anything, batch22.BatchNumber, nil).Return(batch22, nil).Once()
+
+	inputProver, err := a.buildInputProver(context.Background(), batch23)
+	require.NoError(err)
+	assert.Equal(batch22.StateRoot.Bytes(), inputProver.PublicInputs.OldStateRoot)
+
+	inputProver, err = a.buildInputProver(context.Background(), batch24)
+	require.NoError(err)
+	assert.Equal(batch23.StateRoot.Bytes(), inputProver.PublicInputs.OldStateRoot)
+
+	// after a reorg the cached batch 23 doesn't match the new batch 24, so
+	// the new batch 23 is read from the state
+	reorgedBatch23 := newBatch(batch22, "0x230")
+	reorgedBatch24 := newBatch(reorgedBatch23, "0x240")
+	stateMock.On("GetBatchByNumber", mock.Anything, batch23.BatchNumber, nil).Return(reorgedBatch23, nil).Once()
+
+	inputProver, err = a.buildInputProver(context.Background(), reorgedBatch24)
+	require.NoError(err)
+	assert.Equal(reorgedBatch23.StateRoot.Bytes(), inputProver.PublicInputs.OldStateRoot)
+	assert.Equal(reorgedBatch23.AccInputHash.Bytes(), inputProver.PublicInputs.OldAccInputHash)
+
+	// the cache is purged when the state may have been reorged
+	a.batchCache.purge()
+	stateMock.On("GetBatchByNumber", mock.Anything, batch23.BatchNumber, nil).Return(reorgedBatch23, nil).Once()
+
+	_, err = a.buildInputProver(context.Background(), reorgedBatch24)
+	require.NoError(err)
+}
+
+func TestStartFatalError(t *testing.T) {
+	errBanana := errors.New("banana")
+	testCases := []struct {
+		name        string
+		setup       func(mox, *Aggregator)
+		expectedErr string
+	}{
+		{
+			name: "background goroutine reports fatal error",
+			setup: func(m mox, a *Aggregator) {
+				m.ethTxManager.On("ProcessPendingMonitoredTxs", mock.Anything, ethTxManagerOwner, mock.Anything, nil).Once()
+				time.AfterFunc(100*time.Millisecond, func() { a.reportFatal(errBanana) })
+			},
+			expectedErr: "banana",
+		},
+		{
+			name: "failed batch verification tx",
+			setup: func(m mox, a *Aggregator) {
+				m.ethTxManager.On("ProcessPendingMonitoredTxs", mock.Anything, ethTxManagerOwner, mock.Anything, nil).Run(func(args mock.Arguments) {
+					result := ethtxmanager.MonitoredTxResult{
+						ID:     buildMonitoredTxID(23, 42),
+						Status: ethtxmanager.MonitoredTxStatusFailed,
+					}
+					args[2].(ethtxmanager.ResultHandler)(result, nil)
+				}).Once()
+			},
+			expectedErr: "failed to send batch verification, tx proof-from-23-to-42",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := newTestConfig()
+			cfg.Host = "127.0.0.1"
+			ethTxManager := mocks.NewEthTxManager(t)
+			a, err := New(cfg, mocks.NewStateMock(t), ethTxManager, mocks.NewEtherman(t))
+			require.NoError(t, err)
+			tc.setup(mox{ethTxManager: ethTxManager}, &a)
+
+			errCh := make(chan error)
+			go func() { errCh <- a.Start(context.Background()) }()
+
+			select {
+			case err := <-errCh:
+				assert.EqualError(t, err, tc.expectedErr)
+			case <-time.After(5 * time.Second):
+				t.Fatal("Start did not return")
+			}
+			a.srv.Stop()
+		})
+	}
+}
+
+func TestParseMonitoredTxID(t *testing.T) {
+	testCases := []struct {
+		name                     string
+		id                       string
+		expectedBatchNumber      uint64
+		expectedBatchNumberFinal uint64
+		expectedErr              string
+	}{
+		{
+			name:                     "valid",
+			id:                       buildMonitoredTxID(23, 42),
+			expectedBatchNumber:      23,
+			expectedBatchNumberFinal: 42,
+		},
+		{
+			name:        "missing parts",
+			id:          "proof-from-23",
+			expectedErr: `invalid monitored tx ID "proof-from-23"`,
+		},
+		{
+			name:        "hex batch number",
+			id:          "proof-from-0x17-to-42",
+			expectedErr: `invalid batch number in monitored tx ID "proof-from-0x17-to-42": strconv.ParseUint: parsing "0x17": invalid syntax`,
+		},
+		{
+			name:        "leading zeros",
+			id:          "proof-from-023-to-42",
+			expectedErr: `invalid monitored tx ID "proof-from-023-to-42"`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			batchNumber, batchNumberFinal, err := parseMonitoredTxID(tc.id)
+
+			if tc.expectedErr != "" {
+				assert.EqualError(t, err, tc.expectedErr)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, tc.expectedBatchNumber, batchNumber)
+				assert.Equal(t, tc.expectedBatchNumberFinal, batchNumberFinal)
+			}
+		})
+	}
+}
+
+func TestHandleMonitoredTxResultCleanupConfirmations(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+	batchNumFinal := uint64(42)
+	txBlockNumber := uint64(100)
+	cfg := newTestConfig()
+	cfg.CleanupConfirmationBlocks = 5
+	stateMock := mocks.NewStateMock(t)
+	etherman := mocks.NewEtherman(t)
+	a, err := New(cfg, stateMock, mocks.NewEthTxManager(t), etherman)
+	require.NoError(err)
+	a.ctx, a.exit = context.WithCancel(context.Background())
+	defer a.exit()
+	result := ethtxmanager.MonitoredTxResult{
+		ID:     buildMonitoredTxID(23, batchNumFinal),
+		Status: ethtxmanager.MonitoredTxStatusConfirmed,
+		Txs: map[common.Hash]ethtxmanager.TxResult{
+			common.HexToHash("0x1"): {
+				Receipt: &ethTypes.Receipt{
+					Status:      ethTypes.ReceiptStatusSuccessful,
+					BlockNumber: new(big.Int).SetUint64(txBlockNumber),
+				},
+			},
+		},
+	}
+	stateMock.On("GetLastVerifiedBatch", mock.Anything, nil).Return(&state.VerifiedBatch{BatchNumber: batchNumFinal}, nil).Once()
+	etherman.On("GetLatestVerifiedBatchNum").Return(batchNumFinal, nil).Once()
+	notConfirmedCall := etherman.On("GetLatestBlockNumber", mock.Anything).Return(txBlockNumber+cfg.CleanupConfirmationBlocks-1, nil).Once()
+	confirmedCall := etherman.On("GetLatestBlockNumber", mock.Anything).Return(txBlockNumber+cfg.CleanupConfirmationBlocks, nil).Once().NotBefore(notConfirmedCall)
+	cleanedUp := make(chan struct{})
+	stateMock.On("CleanupGeneratedProofs", mock.Anything, batchNumFinal, nil).Return(nil).Once().NotBefore(confirmedCall).
+		Run(func(args mock.Arguments) { close(cleanedUp) })
+
+	// the confirmations are waited in the background
+	a.handleMonitoredTxResult(result)
+
+	select {
+	case <-cleanedUp:
+	case <-time.After(time.Second):
+		t.Fatal("proofs not cleaned up after the confirmations")
+	}
+	etherman.AssertNumberOfCalls(t, "GetLatestBlockNumber", 2)
+	assert.True(stateMock.AssertNumberOfCalls(t, "CleanupGeneratedProofs", 1))
+}
+
+func TestWaitCleanupConfirmationsStopped(t *testing.T) {
+	txBlockNumber := uint64(100)
+	cfg := newTestConfig()
+	cfg.CleanupConfirmationBlocks = 5
+	etherman := mocks.NewEtherman(t)
+	a, err := New(cfg, mocks.NewStateMock(t), mocks.NewEthTxManager(t), etherman)
+	require.NoError(t, err)
+	a.ctx, a.exit = context.WithCancel(context.Background())
+	result := ethtxmanager.MonitoredTxResult{
+		ID: buildMonitoredTxID(23, 42),
+		Txs: map[common.Hash]ethtxmanager.TxResult{
+			common.HexToHash("0x1"): {
+				Receipt: &ethTypes.Receipt{
+					Status:      ethTypes.ReceiptStatusSuccessful,
+					BlockNumber: new(big.Int).SetUint64(txBlockNumber),
+				},
+			},
+		},
+	}
+	etherman.On("GetLatestBlockNumber", mock.Anything).Return(txBlockNumber, nil).
+		Run(func(args mock.Arguments) { a.exit() }).Once()
+
+	assert.False(t, a.waitCleanupConfirmations(result))
+}
+
+func TestHandleResultByID(t *testing.T) {
+	batchNum := uint64(23)
+	batchNumFinal := uint64(42)
+	monitoredTxID := buildMonitoredTxID(batchNum, batchNumFinal)
+	errBanana := errors.New("banana")
+
+	testCases := []struct {
+		name        string
+		setup       func(mox)
+		expectedErr string
+		asserts     func(mox)
+	}{
+		{
+			name: "result error",
+			setup: func(m mox) {
+				m.ethTxManager.On("Result", mock.Anything, ethTxManagerOwner, monitoredTxID, nil).Return(ethtxmanager.MonitoredTxResult{}, errBanana).Once()
+			},
+			expectedErr: fmt.Sprintf("failed to get monitored tx %s result: banana", monitoredTxID),
+		},
+		{
+			name: "not confirmed",
+			setup: func(m mox) {
+				result := ethtxmanager.MonitoredTxResult{ID: monitoredTxID, Status: ethtxmanager.MonitoredTxStatusSent}
+				m.ethTxManager.On("Result", mock.Anything, ethTxManagerOwner, monitoredTxID, nil).Return(result, nil).Once()
+			},
+			expectedErr: fmt.Sprintf("monitored tx %s is not confirmed, status: sent", monitoredTxID),
+		},
+		{
+			name: "confirmed",
+			setup: func(m mox) {
+				result := ethtxmanager.MonitoredTxResult{ID: monitoredTxID, Status: ethtxmanager.MonitoredTxStatusConfirmed}
+				m.ethTxManager.On("Result", mock.Anything, ethTxManagerOwner, monitoredTxID, nil).Return(result, nil).Once()
+				m.stateMock.On("GetLastVerifiedBatch", mock.Anything, nil).Return(&state.VerifiedBatch{BatchNumber: batchNumFinal}, nil).Once()
+				m.etherman.On("GetLatestVerifiedBatchNum").Return(batchNumFinal, nil).Once()
+				m.stateMock.On("CleanupGeneratedProofs", mock.Anything, batchNumFinal, nil).Return(nil).Once()
+			},
+			asserts: func(m mox) {
+				m.stateMock.AssertNumberOfCalls(t, "CleanupGeneratedProofs", 1)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			stateMock := mocks.NewStateMock(t)
+			ethTxManager := mocks.NewEthTxManager(t)
+			etherman := mocks.NewEtherman(t)
+			a, err := New(newTestConfig(), stateMock, ethTxManager, etherman)
+			require.NoError(t, err)
+			a.ctx, a.exit = context.WithCancel(context.Background())
+			defer a.exit()
+			m := mox{
+				stateMock:    stateMock,
+				ethTxManager: ethTxManager,
+				etherman:     etherman,
+			}
+			tc.setup(m)
+
+			err = a.HandleResultByID(context.Background(), monitoredTxID)
+
+			if tc.expectedErr != "" {
+				assert.EqualError(t, err, tc.expectedErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			if tc.asserts != nil {
+				tc.asserts(m)
+			}
+		})
+	}
+}
+
+func TestChannelBlocklistedProver(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+	forkID := uint64(2)
+	cfg := newTestConfig()
+	cfg.ForkId = forkID
+	cfg.ProverMaxConsecutiveFailures = 2
+	cfg.ProverBlocklistDuration = configTypes.NewDuration(time.Minute)
+	// the state mock fails the test if any proof work is attempted
+	a, err := New(cfg, mocks.NewStateMock(t), mocks.NewEthTxManager(t), mocks.NewEtherman(t))
+	require.NoError(err)
+	a.ctx, a.exit = context.WithCancel(context.Background())
+	defer a.exit()
+	proverMock := mocks.NewProverMock(t)
+	proverMock.On("ID").Return("proverID")
+	proverMock.On("Name").Return("proverName").Once()
+	errBanana := errors.New("banana")
+	// the errors not returned by the prover are not counted as its failures
+	a.recordProverFailure(proverMock, errStateLockTimeout)
+	a.recordProverFailure(proverMock, errBanana)
+	a.recordProverFailure(proverMock, fmt.Errorf("failed to get batch proof id, %w", &proverError{errBanana}))
+	a.recordProverFailure(proverMock, fmt.Errorf("failed to get proof from prover, %w", &proverError{errEmptyProof}))
+	streamCtx, cancelStream := context.WithCancel(context.Background())
+	stream := &channelServerMock{
+		ctx: streamCtx,
+		status: &pb.GetStatusResponse{
+			ProverName: "proverName",
+			ProverId:   "proverID",
+			ForkId:     forkID,
+			Status:     pb.GetStatusResponse_STATUS_IDLE,
+		},
+	}
+	time.AfterFunc(100*time.Millisecond, cancelStream)
+
+	err = a.Channel(stream)
+
+	assert.ErrorIs(err, context.Canceled)
+	// only the status requests done on connection, the prover is never asked
+	// if it is idle to receive work
+	assert.Len(stream.sentRequests(), 2)
+}
+
+func TestChannelPaused(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+	forkID := uint64(2)
+	cfg := newTestConfig()
+	cfg.ForkId = forkID
+	// the state mock fails the test if any proof work is attempted
+	a, err := New(cfg, mocks.NewStateMock(t), mocks.NewEthTxManager(t), mocks.NewEtherman(t))
+	require.NoError(err)
+	a.ctx, a.exit = context.WithCancel(context.Background())
+	defer a.exit()
+	streamCtx, cancelStream := context.WithCancel(context.Background())
+	stream := &channelServerMock{
+		ctx: streamCtx,
+		status: &pb.GetStatusResponse{
+			ProverName: "proverName",
+			ProverId:   "proverID",
+			ForkId:     forkID,
+			Status:     pb.GetStatusResponse_STATUS_COMPUTING,
+		},
+	}
+	a.Pause()
+	require.True(a.Paused())
+	time.AfterFunc(100*time.Millisecond, func() {
+		// only the status requests done on connection, the prover is never
+		// asked if it is idle to receive work
+		assert.Len(stream.sentRequests(), 2)
+		a.Resume()
+	})
+	time.AfterFunc(200*time.Millisecond, cancelStream)
+
+	err = a.Channel(stream)
+
+	assert.ErrorIs(err, context.Canceled)
+	assert.False(a.Paused())
+	// once resumed the busy prover is polled to check if it is idle
+	assert.Greater(len(stream.sentRequests()), 2)
+}
+
+func TestChannelProverSession(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+	logFile := filepath.Join(t.TempDir(), "aggregator.log")
+	log.Init(log.Config{Environment: log.EnvironmentProduction, Level: "debug", Outputs: []string{logFile}})
+	defer log.Init(log.Config{Environment: log.EnvironmentDevelopment, Level: "debug", Outputs: []string{"stderr"}})
+	forkID := uint64(2)
+	cfg := newTestConfig()
+	cfg.ForkId = forkID
+	a, err := New(cfg, mocks.NewStateMock(t), mocks.NewEthTxManager(t), mocks.NewEtherman(t))
+	require.NoError(err)
+	a.ctx, a.exit = context.WithCancel(context.Background())
+	defer a.exit()
+	// no proof work is done while paused
+	a.Pause()
+
+	// the same prover connecting twice
+	for i := 0; i < 2; i++ {
+		streamCtx, cancelStream := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		stream := &channelServerMock{
+			ctx: streamCtx,
+			status: &pb.GetStatusResponse{
+				ProverName: "proverName",
+				ProverId:   "proverID",
+				ForkId:     forkID,
+				Status:     pb.GetStatusResponse_STATUS_IDLE,
+			},
+		}
+		err = a.Channel(stream)
+		cancelStream()
+		assert.ErrorIs(err, context.DeadlineExceeded)
+	}
+
+	logs, err := os.ReadFile(logFile)
+	require.NoError(err)
+	var sessions []string
+	for _, line := range strings.Split(strings.TrimSpace(string(logs)), "\n") {
+		var entry map[string]interface{}
+		require.NoError(json.Unmarshal([]byte(line), &entry))
+		if entry["msg"] == "Establishing stream connection with prover" {
+			session, ok := entry["proverSession"].(string)
+			require.True(ok)
+			sessions = append(sessions, session)
+		}
+	}
+	require.Len(sessions, 2)
+	assert.NotEmpty(sessions[0])
+	assert.NotEqual(sessions[0], sessions[1])
+}
+
+func TestReplayInputProver(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+	errBanana := errors.New("banana")
+	proofID := "proofId"
+	recursiveProof := `{"proof":"recursiveProof"}`
+	inputProver := &pb.InputProver{
+		PublicInputs: &pb.PublicInputs{
+			OldStateRoot:    common.HexToHash("0x1").Bytes(),
+			OldAccInputHash: common.HexToHash("0x2").Bytes(),
+			OldBatchNum:     22,
+			ChainId:         1001,
+			ForkId:          2,
+			BatchL2Data:     []byte("batchL2Data"),
+			GlobalExitRoot:  common.HexToHash("0x3").Bytes(),
+			EthTimestamp:    1234,
+			SequencerAddr:   common.HexToAddress("0x4").String(),
+			AggregatorAddr:  common.HexToAddress("0x5").String(),
+		},
+		Db:                map[string]string{},
+		ContractsBytecode: map[string]string{},
+	}
+	b, err := json.Marshal(inputProver)
+	require.NoError(err)
+	protobufInput, err := encodeInputProver(inputProver, InputProverFormatProtobuf)
+	require.NoError(err)
+	matchInputProverFn := func(input *pb.InputProver) bool { return proto.Equal(inputProver, input) }
+	testCases := []struct {
+		name          string
+		input         string
+		setup         func(*mocks.ProverMock)
+		expectedProof string
+		expectedErr   error
+	}{
+		{
+			name:  "stored input replayed",
+			input: string(b),
+			setup: func(m *mocks.ProverMock) {
+				m.On("Name").Return("proverName").Once()
+				m.On("ID").Return("proverID").Once()
+				m.On("BatchProof", mock.MatchedBy(matchInputProverFn)).Return(&proofID, nil).Once()
+				m.On("WaitRecursiveProof", mock.Anything, proofID).Return(recursiveProof, nil).Once()
+			},
+			expectedProof: recursiveProof,
+		},
+		{
+			name:  "stored protobuf input replayed",
+			input: string(protobufInput),
+			setup: func(m *mocks.ProverMock) {
+				m.On("Name").Return("proverName").Once()
+				m.On("ID").Return("proverID").Once()
+				m.On("BatchProof", mock.MatchedBy(matchInputProverFn)).Return(&proofID, nil).Once()
+				m.On("WaitRecursiveProof", mock.Anything, proofID).Return(recursiveProof, nil).Once()
+			},
+			expectedProof: recursiveProof,
+		},
+		{
+			name:  "prover error",
+			input: string(b),
+			setup: func(m *mocks.ProverMock) {
+				m.On("BatchProof", mock.MatchedBy(matchInputProverFn)).Return(nil, errBanana).Once()
+			},
+			expectedErr: errBanana,
+		},
+		{
+			name:  "invalid input",
+			input: "banana",
+		},
+		{
+			name:  "omitted input",
+			input: `{"omitted_input_prover_batch_num":23,"omitted_input_prover_bytes":1000}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			a, err := New(newTestConfig(), mocks.NewStateMock(t), mocks.NewEthTxManager(t), mocks.NewEtherman(t))
+			require.NoError(err)
+			proverMock := mocks.NewProverMock(t)
+			if tc.setup != nil {
+				tc.setup(proverMock)
+			}
+
+			proof, err := a.ReplayInputProver(context.Background(), proverMock, tc.input)
+
+			if tc.expectedProof != "" {
+				assert.NoError(err)
+				assert.Equal(tc.expectedProof, proof)
+			} else {
+				assert.Error(err)
+				if tc.expectedErr != nil {
+					assert.ErrorIs(err, tc.expectedErr)
+				}
+			}
+		})
+	}
+}
+
+func TestGetVirtualBatchToProve(t *testing.T) {
+	errBanana := errors.New("banana")
+	lastVerifiedBatchNum := uint64(22)
+	oldBatch := state.Batch{BatchNumber: 23}
+	lowPriorityBatch := state.Batch{BatchNumber: 30}
+	highPriorityBatch := state.Batch{BatchNumber: 40}
+	priorities := []BatchProofPriority{
+		{FromBatch: 30, ToBatch: 35, Priority: 1},
+		{FromBatch: 40, ToBatch: 45, Priority: 2},
+	}
+	testCases := []struct {
+		name          string
+		priorities    []BatchProofPriority
+		excluded      []BatchRange
+		maxAhead      uint64
+		setup         func(mox)
+		expectedBatch *state.Batch
+		expectedErr   error
+	}{
+		{
+			name: "no priorities, oldest batch",
+			setup: func(m mox) {
+				m.stateMock.On("GetVirtualBatchToProve", mock.Anything, lastVerifiedBatchNum, nil).Return(&oldBatch, nil).Once()
+			},
+			expectedBatch: &oldBatch,
+		},
+		{
+			name:       "higher priority batch chosen over older lower priority ones",
+			priorities: priorities,
+			setup: func(m mox) {
+				m.stateMock.On("GetVirtualBatchToProveInRange", mock.Anything, lastVerifiedBatchNum, uint64(40), uint64(45), nil).Return(&highPriorityBatch, nil).Once()
+			},
+			expectedBatch: &highPriorityBatch,
+		},
+		{
+			name:       "lower priority batch chosen when higher priority range is proved",
+			priorities: priorities,
+			setup: func(m mox) {
+				m.stateMock.On("GetVirtualBatchToProveInRange", mock.Anything, lastVerifiedBatchNum, uint64(40), uint64(45), nil).Return(nil, state.ErrNotFound).Once()
+				m.stateMock.On("GetVirtualBatchToProveInRange", mock.Anything, lastVerifiedBatchNum, uint64(30), uint64(35), nil).Return(&lowPriorityBatch, nil).Once()
+			},
+			expectedBatch: &lowPriorityBatch,
+		},
+		{
+			name:       "oldest batch when all priority ranges are proved",
+			priorities: priorities,
+			setup: func(m mox) {
+				m.stateMock.On("GetVirtualBatchToProveInRange", mock.Anything, lastVerifiedBatchNum, uint64(40), uint64(45), nil).Return(nil, state.ErrNotFound).Once()
+				m.stateMock.On("GetVirtualBatchToProveInRange", mock.Anything, lastVerifiedBatchNum, uint64(30), uint64(35), nil).Return(nil, state.ErrNotFound).Once()
+				m.stateMock.On("GetVirtualBatchToProve", mock.Anything, lastVerifiedBatchNum, nil).Return(&oldBatch, nil).Once()
+			},
+			expectedBatch: &oldBatch,
+		},
+		{
+			name:     "batch after the excluded range chosen",
+			excluded: []BatchRange{{FromBatch: 23, ToBatch: 29}},
+			setup: func(m mox) {
+				m.stateMock.On("GetVirtualBatchToProve", mock.Anything, lastVerifiedBatchNum, nil).Return(&oldBatch, nil).Once()
+				m.stateMock.On("GetVirtualBatchToProveInRange", mock.Anything, lastVerifiedBatchNum, uint64(30), uint64(math.MaxInt64), nil).Return(&lowPriorityBatch, nil).Once()
+			},
+			expectedBatch: &lowPriorityBatch,
+		},
+		{
+			name:       "excluded priority range skipped",
+			priorities: priorities,
+			excluded:   []BatchRange{{FromBatch: 40, ToBatch: 50}},
+			setup: func(m mox) {
+				m.stateMock.On("GetVirtualBatchToProveInRange", mock.Anything, lastVerifiedBatchNum, uint64(40), uint64(45), nil).Return(&highPriorityBatch, nil).Once()
+				m.stateMock.On("GetVirtualBatchToProveInRange", mock.Anything, lastVerifiedBatchNum, uint64(30), uint64(35), nil).Return(&lowPriorityBatch, nil).Once()
+			},
+			expectedBatch: &lowPriorityBatch,
+		},
+		{
+			name:     "nothing to prove after the excluded range",
+			excluded: []BatchRange{{FromBatch: 23, ToBatch: 29}},
+			setup: func(m mox) {
+				m.stateMock.On("GetVirtualBatchToProve", mock.Anything, lastVerifiedBatchNum, nil).Return(&oldBatch, nil).Once()
+				m.stateMock.On("GetVirtualBatchToProveInRange", mock.Anything, lastVerifiedBatchNum, uint64(30), uint64(math.MaxInt64), nil).Return(nil, state.ErrNotFound).Once()
+			},
+			expectedErr: state.ErrNotFound,
+		},
+		{
+			name:     "batch within the prove ahead limit chosen",
+			maxAhead: 1,
+			setup: func(m mox) {
+				m.stateMock.On("GetVirtualBatchToProve", mock.Anything, lastVerifiedBatchNum, nil).Return(&oldBatch, nil).Once()
+			},
+			expectedBatch: &oldBatch,
+		},
+		{
+			name:     "batch beyond the prove ahead limit not chosen",
+			maxAhead: 5,
+			setup: func(m mox) {
+				m.stateMock.On("GetVirtualBatchToProve", mock.Anything, lastVerifiedBatchNum, nil).Return(&lowPriorityBatch, nil).Once()
+			},
+			expectedErr: state.ErrNotFound,
+		},
+		{
+			name:       "priority range beyond the prove ahead limit skipped",
+			priorities: priorities,
+			maxAhead:   10,
+			setup: func(m mox) {
+				m.stateMock.On("GetVirtualBatchToProveInRange", mock.Anything, lastVerifiedBatchNum, uint64(40), uint64(45), nil).Return(&highPriorityBatch, nil).Once()
+				m.stateMock.On("GetVirtualBatchToProveInRange", mock.Anything, lastVerifiedBatchNum, uint64(30), uint64(35), nil).Return(&lowPriorityBatch, nil).Once()
+			},
+			expectedBatch: &lowPriorityBatch,
+		},
+		{
+			name:       "state error",
+			priorities: priorities,
+			setup: func(m mox) {
+				m.stateMock.On("GetVirtualBatchToProveInRange", mock.Anything, lastVerifiedBatchNum, uint64(40), uint64(45), nil).Return(nil, errBanana).Once()
+			},
+			expectedErr: errBanana,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			stateMock := mocks.NewStateMock(t)
+			cfg := newTestConfig()
+			cfg.BatchProofPriorities = tc.priorities
+			cfg.ExcludedBatchRanges = tc.excluded
+			cfg.MaxProveAheadBatches = tc.maxAhead
+			a, err := New(cfg, stateMock, mocks.NewEthTxManager(t), mocks.NewEtherman(t))
+			require.NoError(t, err)
+			tc.setup(mox{stateMock: stateMock})
+
+			batch, err := a.getVirtualBatchToProve(context.Background(), lastVerifiedBatchNum)
+
+			if tc.expectedErr != nil {
+				assert.ErrorIs(t, err, tc.expectedErr)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tc.expectedBatch, batch)
+			}
+		})
+	}
+}
+
+func TestWarnNoProvers(t *testing.T) {
+	testCases := []struct {
+		name         string
+		provers      int
+		expectedWarn bool
+	}{
+		{
+			name:         "no prover connected warns",
+			expectedWarn: true,
+		},
+		{
+			name:    "prover connected does not warn",
+			provers: 1,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			logFile := filepath.Join(t.TempDir(), "aggregator.log")
+			log.Init(log.Config{Environment: log.EnvironmentProduction, Level: "debug", Outputs: []string{logFile}})
+			defer log.Init(log.Config{Environment: log.EnvironmentDevelopment, Level: "debug", Outputs: []string{"stderr"}})
+			cfg := newTestConfig()
+			cfg.NoProverWarnInterval = configTypes.NewDuration(20 * time.Millisecond)
+			a, err := New(cfg, mocks.NewStateMock(t), mocks.NewEthTxManager(t), mocks.NewEtherman(t))
+			require.NoError(t, err)
+			a.ctx, a.exit = context.WithCancel(context.Background())
+			for i := 0; i < tc.provers; i++ {
+				require.True(t, a.acquireProverSlot())
+			}
+			time.AfterFunc(100*time.Millisecond, a.exit)
+
+			a.warnNoProvers()
+
+			logs, err := os.ReadFile(logFile)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedWarn, strings.Contains(string(logs), "No prover connected"))
+		})
+	}
+}
+
+func TestUpdateOldestUnprovenBatchAge(t *testing.T) {
+	zkevmMetrics.Init()
+	metrics.Register()
+	errBanana := errors.New("banana")
+	lastVerifiedBatch := state.VerifiedBatch{BatchNumber: 22}
+	testCases := []struct {
+		name        string
+		setup       func(mox)
+		expectedAge float64
+		expectedErr error
+	}{
+		{
+			name: "oldest unproven batch age",
+			setup: func(m mox) {
+				m.stateMock.On("GetLastVerifiedBatch", mock.Anything, nil).Return(&lastVerifiedBatch, nil).Once()
+				batch := state.Batch{BatchNumber: 23, Timestamp: time.Now().Add(-time.Minute)}
+				m.stateMock.On("GetVirtualBatchToProve", mock.Anything, lastVerifiedBatch.BatchNumber, nil).Return(&batch, nil).Once()
+			},
+			expectedAge: time.Minute.Seconds(),
+		},
+		{
+			name: "no batch pending to be proved",
+			setup: func(m mox) {
+				m.stateMock.On("GetLastVerifiedBatch", mock.Anything, nil).Return(&lastVerifiedBatch, nil).Once()
+				m.stateMock.On("GetVirtualBatchToProve", mock.Anything, lastVerifiedBatch.BatchNumber, nil).Return(nil, state.ErrNotFound).Once()
+			},
+			expectedAge: 0,
+		},
+		{
+			name: "state error",
+			setup: func(m mox) {
+				m.stateMock.On("GetLastVerifiedBatch", mock.Anything, nil).Return(&lastVerifiedBatch, nil).Once()
+				m.stateMock.On("GetVirtualBatchToProve", mock.Anything, lastVerifiedBatch.BatchNumber, nil).Return(nil, errBanana).Once()
+			},
+			expectedErr: errBanana,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			stateMock := mocks.NewStateMock(t)
+			a, err := New(newTestConfig(), stateMock, mocks.NewEthTxManager(t), mocks.NewEtherman(t))
+			require.NoError(t, err)
+			tc.setup(mox{stateMock: stateMock})
+
+			err = a.updateOldestUnprovenBatchAge(context.Background())
+
+			if tc.expectedErr != nil {
+				assert.ErrorIs(t, err, tc.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			gauge, ok := zkevmMetrics.Gauge("aggregator_oldest_unproven_batch_age_seconds")
+			require.True(t, ok)
+			assert.InDelta(t, tc.expectedAge, testutil.ToFloat64(gauge), 1)
+		})
+	}
+}
+
+func TestStateLockTimeout(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.StateLockTimeout = configTypes.NewDuration(50 * time.Millisecond)
+	// the state mock fails the test if the state is accessed without the lock
+	a, err := New(cfg, mocks.NewStateMock(t), mocks.NewEthTxManager(t), mocks.NewEtherman(t))
+	require.NoError(t, err)
+	proverMock := mocks.NewProverMock(t)
+	proverMock.On("Name").Return("proverName").Maybe()
+	proverMock.On("ID").Return("proverID").Maybe()
+	proverMock.On("Addr").Return("addr").Maybe()
+	a.StateDBMutex.Lock()
+	defer a.StateDBMutex.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, err := a.getAndLockProofReadyToVerify(context.Background(), proverMock, 0)
+		assert.ErrorIs(t, err, errStateLockTimeout)
+		_, _, err = a.getAndLockProofsToAggregate(context.Background(), proverMock, "attemptID")
+		assert.ErrorIs(t, err, errStateLockTimeout)
+		_, _, err = a.getAndLockBatchToProve(context.Background(), proverMock, "attemptID")
+		assert.ErrorIs(t, err, errStateLockTimeout)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("lock acquisition blocked indefinitely")
+	}
+}
+
+func TestLogStaleProofs(t *testing.T) {
+	logFile := filepath.Join(t.TempDir(), "aggregator.log")
+	log.Init(log.Config{Environment: log.EnvironmentProduction, Level: "debug", Outputs: []string{logFile}})
+	defer log.Init(log.Config{Environment: log.EnvironmentDevelopment, Level: "debug", Outputs: []string{"stderr"}})
+	cfg := newTestConfig()
+	cfg.GeneratingProofCleanupThreshold = "10m"
+	stateMock := mocks.NewStateMock(t)
+	a, err := New(cfg, stateMock, mocks.NewEthTxManager(t), mocks.NewEtherman(t))
+	require.NoError(t, err)
+	proverName := "proverName"
+	proverID := "proverID"
+	oneHourAgo := time.Now().Add(-time.Hour)
+	stale := []*state.Proof{
+		{BatchNumber: 1, BatchNumberFinal: 3, Prover: &proverName, ProverID: &proverID, GeneratingSince: &oneHourAgo},
+		{BatchNumber: 4, BatchNumberFinal: 4, GeneratingSince: &oneHourAgo},
+	}
+	stateMock.On("GetGeneratingProofs", mock.Anything, 10*time.Minute, nil).Return(stale, nil).Once()
+
+	a.logStaleProofs(context.Background())
+
+	logs, err := os.ReadFile(logFile)
+	require.NoError(t, err)
+	assert.Contains(t, string(logs), "Reclaiming proof 1-3 locked by prover proverName (proverID) for 1h0m0s")
+	assert.Contains(t, string(logs), "Reclaiming proof 4-4 locked by prover  () for 1h0m0s")
+}
+
+func TestUpdateFinalProofGap(t *testing.T) {
+	zkevmMetrics.Init()
+	metrics.Register()
+	testCases := []struct {
+		name                 string
+		batchNumberFinal     uint64
+		lastVerifiedBatchNum uint64
+		expectedGap          float64
+		expectedWarn         bool
+	}{
+		{
+			name:                 "gap below threshold",
+			batchNumberFinal:     25,
+			lastVerifiedBatchNum: 22,
+			expectedGap:          3,
+		},
+		{
+			name:                 "gap over threshold",
+			batchNumberFinal:     42,
+			lastVerifiedBatchNum: 22,
+			expectedGap:          20,
+			expectedWarn:         true,
+		},
+		{
+			name:                 "final batch behind last verified",
+			batchNumberFinal:     20,
+			lastVerifiedBatchNum: 22,
+			expectedGap:          0,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			logFile := filepath.Join(t.TempDir(), "aggregator.log")
+			log.Init(log.Config{Environment: log.EnvironmentProduction, Level: "debug", Outputs: []string{logFile}})
+			defer log.Init(log.Config{Environment: log.EnvironmentDevelopment, Level: "debug", Outputs: []string{"stderr"}})
+			cfg := newTestConfig()
+			cfg.FinalProofGapWarningThreshold = 10
+			a, err := New(cfg, mocks.NewStateMock(t), mocks.NewEthTxManager(t), mocks.NewEtherman(t))
+			require.NoError(t, err)
+
+			a.updateFinalProofGap(tc.batchNumberFinal, tc.lastVerifiedBatchNum)
+
+			gauge, ok := zkevmMetrics.Gauge("aggregator_final_proof_gap_batches")
+			require.True(t, ok)
+			assert.Equal(t, tc.expectedGap, testutil.ToFloat64(gauge))
+			logs, err := os.ReadFile(logFile)
+			require.NoError(t, err)
+			warning := fmt.Sprintf("Building final proof up to batch %d, 20 batches ahead of last verified batch %d", tc.batchNumberFinal, tc.lastVerifiedBatchNum)
+			if tc.expectedWarn {
+				assert.Contains(t, string(logs), warning)
+			} else {
+				assert.NotContains(t, string(logs), "batches ahead of last verified batch")
+			}
+		})
+	}
+}
+
+func TestUpdateFinalProofWait(t *testing.T) {
+	zkevmMetrics.Init()
+	metrics.Register()
+	logFile := filepath.Join(t.TempDir(), "aggregator.log")
+	log.Init(log.Config{Environment: log.EnvironmentProduction, Level: "debug", Outputs: []string{logFile}})
+	defer log.Init(log.Config{Environment: log.EnvironmentDevelopment, Level: "debug", Outputs: []string{"stderr"}})
+	cfg := newTestConfig()
+	cfg.FinalProofWaitWarning = configTypes.NewDuration(time.Hour)
+	a, err := New(cfg, mocks.NewStateMock(t), mocks.NewEthTxManager(t), mocks.NewEtherman(t))
+	require.NoError(t, err)
+	clk := newFakeClock(time.Now())
+	a.clock = clk
+	gauge, ok := zkevmMetrics.Gauge("aggregator_final_proof_wait_seconds")
+	require.True(t, ok)
+
+	a.updateFinalProofWait(23)
+	clk.Advance(time.Minute)
+	a.updateFinalProofWait(23)
+
+	assert.Equal(t, time.Minute.Seconds(), testutil.ToFloat64(gauge))
+	logs, err := os.ReadFile(logFile)
+	require.NoError(t, err)
+	assert.NotContains(t, string(logs), "No proof ready to verify batch 23")
+
+	clk.Advance(time.Hour)
+	a.updateFinalProofWait(23)
+
+	assert.Equal(t, (time.Hour + time.Minute).Seconds(), testutil.ToFloat64(gauge))
+	logs, err = os.ReadFile(logFile)
+	require.NoError(t, err)
+	assert.Contains(t, string(logs), "No proof ready to verify batch 23 for 1h1m0s")
+}
+
+func TestCheckProvingSLA(t *testing.T) {
+	zkevmMetrics.Init()
+	metrics.Register()
+	now := time.Now()
+	errBanana := errors.New("banana")
+	testCases := []struct {
+		name           string
+		sequencedAt    time.Time
+		err            error
+		expectedBreach bool
+	}{
+		{
+			name:        "verified within the SLA",
+			sequencedAt: now.Add(-30 * time.Minute),
+		},
+		{
+			name:           "verified after the SLA",
+			sequencedAt:    now.Add(-2 * time.Hour),
+			expectedBreach: true,
+		},
+		{
+			name: "sequencing time error",
+			err:  errBanana,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := newTestConfig()
+			cfg.ProvingSLA = configTypes.NewDuration(time.Hour)
+			stateMock := mocks.NewStateMock(t)
+			a, err := New(cfg, stateMock, mocks.NewEthTxManager(t), mocks.NewEtherman(t))
+			require.NoError(t, err)
+			a.clock = newFakeClock(now)
+			stateMock.On("GetVirtualBatchTimestamp", mock.Anything, uint64(23), nil).Return(tc.sequencedAt, tc.err).Once()
+			counter, ok := zkevmMetrics.Counter("aggregator_proving_sla_breaches")
+			require.True(t, ok)
+			before := testutil.ToFloat64(counter)
+
+			a.checkProvingSLA(context.Background(), 23, 42)
+
+			var expected float64
+			if tc.expectedBreach {
+				expected = 1
+			}
+			assert.Equal(t, before+expected, testutil.ToFloat64(counter))
+		})
+	}
+}
+
+func TestValidateEligibleFinalProofDeletionGrace(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.ProofDeletionGrace = configTypes.NewDuration(time.Minute)
+	cfg.DiscardOverlappingFinalProofs = true
+	stateMock := mocks.NewStateMock(t)
+	a, err := New(cfg, stateMock, mocks.NewEthTxManager(t), mocks.NewEtherman(t))
+	require.NoError(t, err)
+	clk := newFakeClock(time.Now())
+	a.clock = clk
+	proofID := "proofId"
+	staleProof := &state.Proof{ProofID: &proofID, BatchNumber: 20, BatchNumberFinal: 30}
+	lastVerifiedBatchNum := uint64(22)
+
+	// freshly stale proof kept
+	eligible, err := a.validateEligibleFinalProof(context.Background(), staleProof, lastVerifiedBatchNum)
+	require.NoError(t, err)
+	assert.False(t, eligible)
+	clk.Advance(30 * time.Second)
+	eligible, err = a.validateEligibleFinalProof(context.Background(), staleProof, lastVerifiedBatchNum)
+	require.NoError(t, err)
+	assert.False(t, eligible)
+
+	// stale for the whole grace window, deleted
+	clk.Advance(30 * time.Second)
+	stateMock.On("DeleteGeneratedProofs", mock.Anything, staleProof.BatchNumber, staleProof.BatchNumberFinal, nil).Return(nil).Once()
+	eligible, err = a.validateEligibleFinalProof(context.Background(), staleProof, lastVerifiedBatchNum)
+	require.NoError(t, err)
+	assert.False(t, eligible)
+}
+
+func TestGetAndLockBatchToProveProfitability(t *testing.T) {
+	forcedBatchNum := uint64(3)
+	lastVerifiedBatch := state.VerifiedBatch{BatchNumber: 22}
+	testCases := []struct {
+		name          string
+		batch         state.Batch
+		setup         func(*mocks.ProfitabilityCheckerMock, *mocks.StateMock)
+		expectedBatch bool
+		expectedErr   error
+	}{
+		{
+			name:  "unprofitable batch skipped",
+			batch: state.Batch{BatchNumber: 23},
+			setup: func(pc *mocks.ProfitabilityCheckerMock, m *mocks.StateMock) {
+				pc.On("IsProfitable", mock.Anything, big.NewInt(0)).Return(false, nil).Once()
+			},
+			expectedErr: state.ErrNotFound,
+		},
+		{
+			name:  "forced batch claimed without profitability check",
+			batch: state.Batch{BatchNumber: 23, ForcedBatchNum: &forcedBatchNum},
+			setup: func(pc *mocks.ProfitabilityCheckerMock, m *mocks.StateMock) {
+				m.On("AddGeneratedProof", mock.Anything, mock.Anything, nil).Return(nil).Once()
+			},
+			expectedBatch: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			stateMock := mocks.NewStateMock(t)
+			profitabilityChecker := mocks.NewProfitabilityCheckerMock(t)
+			a, err := New(newTestConfig(), stateMock, mocks.NewEthTxManager(t), mocks.NewEtherman(t))
+			require.NoError(t, err)
+			a.ProfitabilityChecker = profitabilityChecker
+			proverMock := mocks.NewProverMock(t)
+			proverMock.On("Name").Return("proverName").Once()
+			proverMock.On("ID").Return("proverID").Once()
+			proverMock.On("Addr").Return("addr").Once()
+			batch := tc.batch
+			stateMock.On("GetLastVerifiedBatch", mock.Anything, nil).Return(&lastVerifiedBatch, nil).Once()
+			stateMock.On("GetVirtualBatchToProve", mock.Anything, lastVerifiedBatch.BatchNumber, nil).Return(&batch, nil).Once()
+			tc.setup(profitabilityChecker, stateMock)
+
+			batchToProve, proof, err := a.getAndLockBatchToProve(context.Background(), proverMock, "attemptID")
+
+			if tc.expectedErr != nil {
+				assert.ErrorIs(t, err, tc.expectedErr)
+			} else {
+				require.NoError(t, err)
+			}
+			if tc.expectedBatch {
+				assert.Equal(t, &batch, batchToProve)
+				require.NotNil(t, proof)
+				assert.Equal(t, batch.BatchNumber, proof.BatchNumber)
+			} else {
+				assert.Nil(t, batchToProve)
+				assert.Nil(t, proof)
+			}
+		})
+	}
+}
+
+func TestCheckSequencedAccInputHash(t *testing.T) {
+	errBanana := errors.New("banana")
+	batch := state.Batch{BatchNumber: 23, AccInputHash: common.HexToHash("0x1")}
+	testCases := []struct {
+		name         string
+		accInputHash common.Hash
+		err          error
+		expectedErr  error
+	}{
+		{
+			name:         "matching accumulated input hash",
+			accInputHash: batch.AccInputHash,
+		},
+		{
+			name:         "mismatching accumulated input hash",
+			accInputHash: common.HexToHash("0x2"),
+			expectedErr:  errAccInputHashMismatch,
+		},
+		{
+			name: "batch not closing a sequence",
+		},
+		{
+			name:        "L1 error",
+			err:         errBanana,
+			expectedErr: errBanana,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			etherman := mocks.NewEtherman(t)
+			a, err := New(newTestConfig(), mocks.NewStateMock(t), mocks.NewEthTxManager(t), etherman)
+			require.NoError(t, err)
+			etherman.On("GetSequencedBatchAccInputHash", mock.Anything, batch.BatchNumber).Return(tc.accInputHash, tc.err).Once()
+
+			err = a.checkSequencedAccInputHash(context.Background(), &batch)
+
+			if tc.expectedErr != nil {
+				assert.ErrorIs(t, err, tc.expectedErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestProverLockLimit(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.MaxLockedBatchesPerProver = 1
+	// the state mock fails the test if a proof is looked for or locked
+	a, err := New(cfg, mocks.NewStateMock(t), mocks.NewEthTxManager(t), mocks.NewEtherman(t))
+	require.NoError(t, err)
+	proverMock := mocks.NewProverMock(t)
+	proverMock.On("Name").Return("proverName").Maybe()
+	proverMock.On("ID").Return("proverID").Maybe()
+	proverMock.On("Addr").Return("addr").Maybe()
+	a.proverLocks.add("proverID")
+
+	_, _, err = a.getAndLockBatchToProve(context.Background(), proverMock, "attemptID")
+	assert.ErrorIs(t, err, state.ErrNotFound)
+	_, _, err = a.getAndLockProofsToAggregate(context.Background(), proverMock, "attemptID")
+	assert.ErrorIs(t, err, state.ErrNotFound)
+}
+
+func TestIsSyncedStateAheadOfL1Metric(t *testing.T) {
+	zkevmMetrics.Init()
+	metrics.Register()
+	stateMock := mocks.NewStateMock(t)
+	etherman := mocks.NewEtherman(t)
+	a, err := New(newTestConfig(), stateMock, mocks.NewEthTxManager(t), etherman)
+	require.NoError(t, err)
+	counter, ok := zkevmMetrics.Counter("aggregator_state_ahead_of_l1")
+	require.True(t, ok)
+	before := testutil.ToFloat64(counter)
+	stateMock.On("GetLastVerifiedBatch", mock.Anything, nil).Return(&state.VerifiedBatch{BatchNumber: 42}, nil).Once()
+	etherman.On("GetLatestVerifiedBatchNum").Return(uint64(40), nil).Once()
+
+	synced := a.isSynced(context.Background(), nil)
+
+	assert.False(t, synced)
+	assert.Equal(t, before+1, testutil.ToFloat64(counter))
+}
+
+func TestIsSyncedStateAheadOfL1Timeout(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.MaxStateAheadOfL1 = configTypes.NewDuration(time.Minute)
+	stateMock := mocks.NewStateMock(t)
+	etherman := mocks.NewEtherman(t)
+	a, err := New(cfg, stateMock, mocks.NewEthTxManager(t), etherman)
+	require.NoError(t, err)
+	clk := newFakeClock(time.Now())
+	a.clock = clk
+	stateMock.On("GetLastVerifiedBatch", mock.Anything, nil).Return(&state.VerifiedBatch{BatchNumber: 42}, nil)
+	etherman.On("GetLatestVerifiedBatchNum").Return(uint64(40), nil)
+
+	// ahead of L1 for less than the maximum, waiting for the synchronizer
+	assert.False(t, a.isSynced(context.Background(), nil))
+	clk.Advance(time.Minute)
+	assert.False(t, a.isSynced(context.Background(), nil))
+	assert.Empty(t, a.fatalErr)
+
+	clk.Advance(time.Second)
+	assert.False(t, a.isSynced(context.Background(), nil))
+	select {
+	case err := <-a.fatalErr:
+		assert.ErrorContains(t, err, "state ahead of L1 verification")
+	default:
+		t.Fatal("state ahead of L1 for too long not reported as fatal")
+	}
+}
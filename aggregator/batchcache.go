@@ -0,0 +1,107 @@
+package aggregator
+
+import (
+	"container/list"
+	"encoding/binary"
+	"sync"
+
+	"github.com/0xPolygonHermez/zkevm-node/state"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// batchCacheSize is the number of batches kept by the batch cache when
+// enabled.
+const batchCacheSize = 16
+
+// batchCache is a LRU cache of the batches recently read to build the prover
+// inputs, so proving consecutive batches does not read the same batch from the
+// state again. The cached batches may be outdated after a reorg, so a cached
+// previous batch is only used if the batch accumulated input hash derives
+// from it. A size of 0 disables it.
+type batchCache struct {
+	mu      sync.Mutex
+	size    int
+	order   *list.List
+	batches map[uint64]*list.Element
+}
+
+func newBatchCache(size int) *batchCache {
+	return &batchCache{
+		size:    size,
+		order:   list.New(),
+		batches: make(map[uint64]*list.Element),
+	}
+}
+
+// get returns the cached batch with the given number, if any.
+func (c *batchCache) get(batchNumber uint64) (*state.Batch, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.batches[batchNumber]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(e)
+	return e.Value.(*state.Batch), true
+}
+
+// previous returns the cached previous batch of the provided batch, if any and
+// its accumulated input hash matches the one the batch was sequenced with.
+func (c *batchCache) previous(batch *state.Batch) (*state.Batch, bool) {
+	previousBatch, ok := c.get(batch.BatchNumber - 1)
+	if !ok {
+		return nil, false
+	}
+	if accInputHash(previousBatch.AccInputHash, batch) != batch.AccInputHash {
+		return nil, false
+	}
+	return previousBatch, true
+}
+
+// add caches the batch, evicting the least recently used one if the cache is
+// full.
+func (c *batchCache) add(batch *state.Batch) {
+	if c.size <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.batches[batch.BatchNumber]; ok {
+		e.Value = batch
+		c.order.MoveToFront(e)
+		return
+	}
+	c.batches[batch.BatchNumber] = c.order.PushFront(batch)
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.batches, oldest.Value.(*state.Batch).BatchNumber)
+	}
+}
+
+// purge empties the cache, when the cached batches may have been reorged.
+func (c *batchCache) purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.order.Init()
+	c.batches = make(map[uint64]*list.Element)
+}
+
+// accInputHash returns the accumulated input hash of the batch given the one
+// of its previous batch, as computed by the rollup contract on sequencing.
+func accInputHash(oldAccInputHash common.Hash, batch *state.Batch) common.Hash {
+	var timestamp [8]byte
+	binary.BigEndian.PutUint64(timestamp[:], uint64(batch.Timestamp.Unix()))
+	return common.BytesToHash(crypto.Keccak256(
+		oldAccInputHash.Bytes(),
+		crypto.Keccak256(batch.BatchL2Data),
+		batch.GlobalExitRoot.Bytes(),
+		timestamp[:],
+		batch.Coinbase.Bytes(),
+	))
+}
@@ -0,0 +1,65 @@
+package aggregator
+
+import (
+	"testing"
+
+	"github.com/0xPolygonHermez/zkevm-node/state"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBatchCache(t *testing.T) {
+	assert := assert.New(t)
+	c := newBatchCache(2)
+	batch1 := &state.Batch{BatchNumber: 1}
+	batch2 := &state.Batch{BatchNumber: 2}
+	batch3 := &state.Batch{BatchNumber: 3}
+
+	c.add(batch1)
+	c.add(batch2)
+	cached, ok := c.get(1)
+	assert.True(ok)
+	assert.Same(batch1, cached)
+
+	// batch 2 is the least recently used
+	c.add(batch3)
+	_, ok = c.get(2)
+	assert.False(ok)
+	_, ok = c.get(1)
+	assert.True(ok)
+	_, ok = c.get(3)
+	assert.True(ok)
+
+	c.purge()
+	_, ok = c.get(1)
+	assert.False(ok)
+	_, ok = c.get(3)
+	assert.False(ok)
+}
+
+func TestBatchCachePrevious(t *testing.T) {
+	assert := assert.New(t)
+	c := newBatchCache(2)
+	batch1 := &state.Batch{BatchNumber: 1, AccInputHash: common.HexToHash("0x1")}
+	batch2 := &state.Batch{BatchNumber: 2, BatchL2Data: []byte{0x2}}
+	batch2.AccInputHash = accInputHash(batch1.AccInputHash, batch2)
+	c.add(batch1)
+
+	previous, ok := c.previous(batch2)
+	assert.True(ok)
+	assert.Same(batch1, previous)
+
+	// a batch 2 sequenced on top of another batch 1
+	batch2.AccInputHash = accInputHash(common.HexToHash("0x10"), batch2)
+	_, ok = c.previous(batch2)
+	assert.False(ok)
+}
+
+func TestBatchCacheDisabled(t *testing.T) {
+	c := newBatchCache(0)
+
+	c.add(&state.Batch{BatchNumber: 1})
+
+	_, ok := c.get(1)
+	assert.False(t, ok)
+}
@@ -0,0 +1,20 @@
+package aggregator
+
+import "time"
+
+// clock provides the current time and timers to the aggregator, so the time
+// dependent logic can be tested deterministically with a fake clock.
+type clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+// realClock is the clock backed by the time package.
+type realClock struct{}
+
+// Now returns the current local time.
+func (realClock) Now() time.Time { return time.Now() }
+
+// After waits for the duration to elapse and then sends the current time on
+// the returned channel.
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
@@ -0,0 +1,117 @@
+package aggregator
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/0xPolygonHermez/zkevm-node/aggregator/mocks"
+	configTypes "github.com/0xPolygonHermez/zkevm-node/config/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClock is a clock whose time only moves forward when advanced.
+type fakeClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []fakeClockWaiter
+}
+
+type fakeClockWaiter struct {
+	deadline time.Time
+	ch       chan time.Time
+}
+
+func newFakeClock(now time.Time) *fakeClock {
+	return &fakeClock{now: now}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ch := make(chan time.Time, 1)
+	c.waiters = append(c.waiters, fakeClockWaiter{deadline: c.now.Add(d), ch: ch})
+	c.fire()
+	return ch
+}
+
+// Advance moves the clock forward, firing the timers that expire.
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	c.fire()
+}
+
+func (c *fakeClock) fire() {
+	pending := c.waiters[:0]
+	for _, w := range c.waiters {
+		if c.now.Before(w.deadline) {
+			pending = append(pending, w)
+			continue
+		}
+		w.ch <- c.now
+	}
+	c.waiters = pending
+}
+
+func TestCanVerifyProofFakeClock(t *testing.T) {
+	assert := assert.New(t)
+	cfg := newTestConfig()
+	cfg.VerifyProofInterval = configTypes.NewDuration(10 * time.Second)
+	a, err := New(cfg, mocks.NewStateMock(t), mocks.NewEthTxManager(t), mocks.NewEtherman(t))
+	require.NoError(t, err)
+	clk := newFakeClock(time.Unix(1_000_000, 0))
+	a.clock = clk
+
+	a.resetVerifyProofTime()
+
+	assert.Equal(clk.Now().Add(10*time.Second), a.TimeSendFinalProof)
+	assert.False(a.canVerifyProof())
+	clk.Advance(10 * time.Second)
+	assert.False(a.canVerifyProof())
+	clk.Advance(time.Nanosecond)
+	assert.True(a.canVerifyProof())
+	a.startProofVerification()
+	assert.False(a.canVerifyProof())
+	a.endProofVerification()
+	assert.True(a.canVerifyProof())
+}
+
+func TestTryLockWithTimeoutFakeClock(t *testing.T) {
+	cfg := newTestConfig()
+	a, err := New(cfg, mocks.NewStateMock(t), mocks.NewEthTxManager(t), mocks.NewEtherman(t))
+	require.NoError(t, err)
+	clk := newFakeClock(time.Unix(1_000_000, 0))
+	a.clock = clk
+	a.StateDBMutex.Lock()
+	defer a.StateDBMutex.Unlock()
+
+	start := clk.Now()
+	done := make(chan error, 1)
+	go func() {
+		done <- a.tryLockWithTimeout(time.Minute)
+	}()
+
+	// the fake clock is moved forward until the wait gives up
+	giveUp := time.After(5 * time.Second)
+	for {
+		select {
+		case err := <-done:
+			assert.ErrorIs(t, err, errStateLockTimeout)
+			assert.False(t, clk.Now().Before(start.Add(time.Minute)))
+			return
+		case <-giveUp:
+			t.Fatal("lock acquisition did not give up")
+		case <-time.After(time.Millisecond):
+			clk.Advance(time.Second)
+		}
+	}
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/0xPolygonHermez/zkevm-node/config/types"
 	"github.com/0xPolygonHermez/zkevm-node/encoding"
+	"github.com/ethereum/go-ethereum/common"
 )
 
 // TokenAmountWithDecimals is a wrapper type that parses token amount with decimals to big int
@@ -28,6 +29,42 @@ func (t *TokenAmountWithDecimals) UnmarshalText(data []byte) error {
 	return nil
 }
 
+// BatchProofPriority sets the priority to prove the batches in the range
+// [FromBatch, ToBatch].
+type BatchProofPriority struct {
+	FromBatch uint64 `mapstructure:"FromBatch"`
+	ToBatch   uint64 `mapstructure:"ToBatch"`
+	Priority  int    `mapstructure:"Priority"`
+}
+
+// BatchRange is the range of batches [FromBatch, ToBatch].
+type BatchRange struct {
+	FromBatch uint64 `mapstructure:"FromBatch"`
+	ToBatch   uint64 `mapstructure:"ToBatch"`
+}
+
+// ProverTransport is the way the generated proofs are got from the provers.
+type ProverTransport string
+
+const (
+	// ProverTransportGRPC gets the proofs over the prover stream.
+	ProverTransportGRPC = "grpc"
+	// ProverTransportFile reads the proofs from a directory shared with the
+	// provers.
+	ProverTransportFile = "file"
+)
+
+// InputProverFormat is the format the batch proof inputs are stored in.
+type InputProverFormat string
+
+const (
+	// InputProverFormatJSON stores the inputs as JSON.
+	InputProverFormatJSON = "json"
+	// InputProverFormatProtobuf stores the inputs as base64 encoded protobuf,
+	// more compact than JSON.
+	InputProverFormatProtobuf = "protobuf"
+)
+
 // Config represents the configuration of the aggregator
 type Config struct {
 	// Host for the grpc server
@@ -42,7 +79,8 @@ type Config struct {
 	// VerifyProofInterval is the interval of time to verify/send an proof in L1
 	VerifyProofInterval types.Duration `mapstructure:"VerifyProofInterval"`
 
-	// ProofStatePollingInterval is the interval time to polling the prover about the generation state of a proof
+	// ProofStatePollingInterval is the initial interval time to polling the prover about the generation state of a proof.
+	// The interval grows for long running proofs, up to 10 times this value.
 	ProofStatePollingInterval types.Duration `mapstructure:"ProofStatePollingInterval"`
 
 	// TxProfitabilityCheckerType type for checking is it profitable for aggregator to validate batch
@@ -73,4 +111,319 @@ type Config struct {
 	// which a proof in generating state is considered to be stuck and
 	// allowed to be cleared.
 	GeneratingProofCleanupThreshold string `mapstructure:"GeneratingProofCleanupThreshold"`
+
+	// CleanupUngeneratedOnStart indicates if the proofs that were being
+	// generated when the aggregator stopped must be deleted on startup. It
+	// must be disabled when several aggregator instances share the same
+	// database, otherwise an instance starting would delete the proofs the
+	// others are generating.
+	CleanupUngeneratedOnStart bool `mapstructure:"CleanupUngeneratedOnStart"`
+
+	// VerifyStoredProofsOnStartup enables checking on startup that the stored
+	// generated proofs are valid JSON. The invalid ones, e.g. partially
+	// written before a crash, are deleted so they are generated again.
+	VerifyStoredProofsOnStartup bool `mapstructure:"VerifyStoredProofsOnStartup"`
+
+	// ProverWarmupDelay is the time to wait since a prover stream is
+	// established before sending it the first proof, so the prover has time to
+	// load its proving keys.
+	ProverWarmupDelay types.Duration `mapstructure:"ProverWarmupDelay"`
+
+	// MaxBatchesPerFinalProof is the maximum number of batches a proof can
+	// span to be used to build a final proof, to avoid exceeding the L1
+	// calldata and gas limits. Proofs are not aggregated beyond it, so it
+	// must not be lower than the batches of the longest sequence. 0 means no
+	// limit.
+	MaxBatchesPerFinalProof uint64 `mapstructure:"MaxBatchesPerFinalProof"`
+
+	// CleanupConfirmationBlocks is the number of L1 blocks that must be mined
+	// on top of the block including a batch verification tx before the
+	// recursive proofs of the verified batches are cleaned up, so they can be
+	// resubmitted if the verification is reorged out. 0 means no wait.
+	CleanupConfirmationBlocks uint64 `mapstructure:"CleanupConfirmationBlocks"`
+
+	// ProverMaxConsecutiveFailures is the number of consecutive proof
+	// generation failures after which a prover stops receiving new work for
+	// ProverBlocklistDuration. 0 means provers are never blocklisted.
+	ProverMaxConsecutiveFailures int `mapstructure:"ProverMaxConsecutiveFailures"`
+
+	// ProverBlocklistDuration is the time a prover is kept without new work
+	// once it reaches ProverMaxConsecutiveFailures.
+	ProverBlocklistDuration types.Duration `mapstructure:"ProverBlocklistDuration"`
+
+	// BatchProofPriorities are the batch ranges to prove before any other
+	// batch, the ranges with a higher priority first. The batches out of
+	// these ranges are proven in order.
+	BatchProofPriorities []BatchProofPriority `mapstructure:"BatchProofPriorities"`
+
+	// NoProverWarnInterval is the interval to log a warning while no prover
+	// is connected, since no proofs can be generated. 0 disables the warning.
+	NoProverWarnInterval types.Duration `mapstructure:"NoProverWarnInterval"`
+
+	// StateLockTimeout is the maximum time to wait for the lock that
+	// serializes the selection of proofs and batches to prove. The operation
+	// is retried later if it can't be acquired in time. 0 waits indefinitely.
+	StateLockTimeout types.Duration `mapstructure:"StateLockTimeout"`
+
+	// OldestUnprovenBatchAgeInterval is the interval to update the metric
+	// with the age of the oldest virtual batch pending to be proved. 0
+	// disables the metric.
+	OldestUnprovenBatchAgeInterval types.Duration `mapstructure:"OldestUnprovenBatchAgeInterval"`
+
+	// L1FailureThreshold is the number of consecutive failed L1 calls after
+	// which the L1 calls are short-circuited for L1BreakerCooldown. 0
+	// disables the circuit breaker.
+	L1FailureThreshold int `mapstructure:"L1FailureThreshold"`
+
+	// L1BreakerCooldown is the time the L1 calls are short-circuited once
+	// L1FailureThreshold is reached.
+	L1BreakerCooldown types.Duration `mapstructure:"L1BreakerCooldown"`
+
+	// MaxLockedBatchesPerProver is the maximum number of proof generations,
+	// batch proofs or aggregations, a prover can have locked in the state at
+	// the same time. 0 means no limit.
+	MaxLockedBatchesPerProver int `mapstructure:"MaxLockedBatchesPerProver"`
+
+	// LocalVerifyBeforeSubmit enables checking the final proof against the
+	// verifier contract with an eth_call before sending the batch
+	// verification tx. A rejected proof is discarded to be generated again.
+	LocalVerifyBeforeSubmit bool `mapstructure:"LocalVerifyBeforeSubmit"`
+
+	// MaxInputProverBytes is the maximum size of the serialized batch proof
+	// input stored along with the proof. Bigger inputs are still sent to the
+	// prover, but only a reference to the batch is stored. 0 means no limit.
+	MaxInputProverBytes uint64 `mapstructure:"MaxInputProverBytes"`
+
+	// DiscardOverlappingFinalProofs enforces the strict ordering of final
+	// proofs: a recursive proof starting below the next batch to verify is
+	// deleted as stale even if it covers it, instead of being used to build a
+	// final proof.
+	DiscardOverlappingFinalProofs bool `mapstructure:"DiscardOverlappingFinalProofs"`
+
+	// MaxStateAheadOfL1 is the maximum time the last verified batch of the
+	// state can stay ahead of the one in L1, waiting for the synchronizer to
+	// handle the reorg of the L1 verifications. Once exceeded the aggregator
+	// stops with an error. 0, the default, means no limit.
+	MaxStateAheadOfL1 types.Duration `mapstructure:"MaxStateAheadOfL1"`
+
+	// ProofDeletionGrace is the time a proof starting below the next batch
+	// to verify must have been found stale before it is deleted, so a brief
+	// desync between the state and L1 doesn't delete needed proofs. 0
+	// deletes them right away.
+	ProofDeletionGrace types.Duration `mapstructure:"ProofDeletionGrace"`
+
+	// FinalProofGapWarningThreshold is the number of batches between the last
+	// final proof built and the last verified batch above which a warning is
+	// logged. 0 means no warning.
+	FinalProofGapWarningThreshold uint64 `mapstructure:"FinalProofGapWarningThreshold"`
+
+	// FinalProofWaitWarning is the time the next batch to verify can wait
+	// for a proof ready to verify before a warning is logged on every check.
+	// 0 disables the warning.
+	FinalProofWaitWarning types.Duration `mapstructure:"FinalProofWaitWarning"`
+
+	// ProverTransport is the way the generated proofs are got from the
+	// provers, possible values: grpc/file. Defaults to grpc.
+	ProverTransport ProverTransport `mapstructure:"ProverTransport"`
+
+	// ProverProofDir is the directory the provers write the generated proofs
+	// to when ProverTransport is file.
+	ProverProofDir string `mapstructure:"ProverProofDir"`
+
+	// ProvingSLA is the maximum time expected between a batch being
+	// sequenced on L1 and its verification. Verifications exceeding it are
+	// reported as SLA breaches. 0 disables the check.
+	ProvingSLA types.Duration `mapstructure:"ProvingSLA"`
+
+	// MinSenderBalance is the minimum L1 balance, in ETH, the sender must
+	// have to pay for the batch verification txs. No final proof is built
+	// while the balance is below it. Empty disables the check.
+	MinSenderBalance TokenAmountWithDecimals `mapstructure:"MinSenderBalance"`
+
+	// ValidateSequencedAccInputHash enables checking, before proving a batch
+	// closing a sequence, that its accumulated input hash in the state matches
+	// the one stored on L1. The batch is not proved on mismatch.
+	ValidateSequencedAccInputHash bool `mapstructure:"ValidateSequencedAccInputHash"`
+
+	// MaxConnectedProvers is the maximum number of prover streams that can be
+	// connected at the same time. Any new stream over this limit is rejected.
+	// 0 means no limit.
+	MaxConnectedProvers int `mapstructure:"MaxConnectedProvers"`
+
+	// MaxLoggedProofLength is the maximum number of characters of a proof,
+	// or of a prover response carrying one, included in logs and errors.
+	// Longer ones are truncated. 0 means no truncation.
+	MaxLoggedProofLength int `mapstructure:"MaxLoggedProofLength"`
+
+	// EnableBatchCache enables caching the batches recently read to build the
+	// prover inputs, saving a state read of the previous batch when proving
+	// consecutive batches. A cached batch is only used while the accumulated
+	// input hash of the batch to prove derives from it.
+	EnableBatchCache bool `mapstructure:"EnableBatchCache"`
+
+	// ExcludedBatchRanges are the batch ranges never selected for proving,
+	// e.g. known bad batches pending a manual fix. The batches after an
+	// excluded range are still proved, but can't be verified until the
+	// range is.
+	ExcludedBatchRanges []BatchRange `mapstructure:"ExcludedBatchRanges"`
+
+	// InputProverStorageFormat is the format the batch proof inputs are
+	// stored in along with the proofs, possible values: json/protobuf.
+	// Defaults to json.
+	InputProverStorageFormat InputProverFormat `mapstructure:"InputProverStorageFormat"`
+
+	// ExportVerifyCalldataDir is the directory the batch verification txs are
+	// written to, besides being sent, so they can be submitted manually if
+	// the eth tx manager is down. Empty disables the export.
+	ExportVerifyCalldataDir string `mapstructure:"ExportVerifyCalldataDir"`
+
+	// MaxProveAheadBatches is the maximum number of batches after the last
+	// verified batch that can be proved. The provers idle once the next
+	// batch to prove is further ahead. 0 means no limit.
+	MaxProveAheadBatches uint64 `mapstructure:"MaxProveAheadBatches"`
+
+	// MaxAggregationRetries is the number of consecutive failures aggregating
+	// the same pair of proofs after which the pair is quarantined and no
+	// longer aggregated until the aggregator restarts. 0 means pairs are never
+	// quarantined.
+	MaxAggregationRetries int `mapstructure:"MaxAggregationRetries"`
+
+	// UnlockProofsMaxRetries is the number of times releasing the proofs of a
+	// failed aggregation is retried, with an exponential backoff, before
+	// giving up and leaving them to the locked proofs cleanup. 0 means no
+	// retries.
+	UnlockProofsMaxRetries int `mapstructure:"UnlockProofsMaxRetries"`
+}
+
+// Validate checks that the configuration is usable, returning an error that
+// describes the first invalid field found.
+func (c Config) Validate() error {
+	positiveDurations := []struct {
+		name     string
+		duration types.Duration
+	}{
+		{"RetryTime", c.RetryTime},
+		{"ProofStatePollingInterval", c.ProofStatePollingInterval},
+		{"CleanupLockedProofsInterval", c.CleanupLockedProofsInterval},
+	}
+	for _, d := range positiveDurations {
+		if d.duration.Duration <= 0 {
+			return fmt.Errorf("%s must be positive, got %s", d.name, d.duration.Duration)
+		}
+	}
+
+	nonNegativeDurations := []struct {
+		name     string
+		duration types.Duration
+	}{
+		{"VerifyProofInterval", c.VerifyProofInterval},
+		{"IntervalAfterWhichBatchConsolidateAnyway", c.IntervalAfterWhichBatchConsolidateAnyway},
+		{"ProverWarmupDelay", c.ProverWarmupDelay},
+		{"ProverBlocklistDuration", c.ProverBlocklistDuration},
+		{"NoProverWarnInterval", c.NoProverWarnInterval},
+		{"OldestUnprovenBatchAgeInterval", c.OldestUnprovenBatchAgeInterval},
+		{"FinalProofWaitWarning", c.FinalProofWaitWarning},
+		{"ProvingSLA", c.ProvingSLA},
+		{"ProofDeletionGrace", c.ProofDeletionGrace},
+		{"MaxStateAheadOfL1", c.MaxStateAheadOfL1},
+		{"StateLockTimeout", c.StateLockTimeout},
+		{"L1BreakerCooldown", c.L1BreakerCooldown},
+	}
+	for _, d := range nonNegativeDurations {
+		if d.duration.Duration < 0 {
+			return fmt.Errorf("%s must not be negative, got %s", d.name, d.duration.Duration)
+		}
+	}
+
+	switch c.TxProfitabilityCheckerType {
+	case ProfitabilityBase:
+		if c.TxProfitabilityMinReward.Int == nil {
+			return fmt.Errorf("TxProfitabilityMinReward is required for the %q profitability checker", ProfitabilityBase)
+		}
+	case ProfitabilityAcceptAll:
+	default:
+		return fmt.Errorf("unknown TxProfitabilityCheckerType %q", c.TxProfitabilityCheckerType)
+	}
+
+	switch c.ProverTransport {
+	case "", ProverTransportGRPC:
+	case ProverTransportFile:
+		if c.ProverProofDir == "" {
+			return fmt.Errorf("ProverProofDir is required for the %q prover transport", ProverTransportFile)
+		}
+	default:
+		return fmt.Errorf("unknown ProverTransport %q", c.ProverTransport)
+	}
+
+	switch c.InputProverStorageFormat {
+	case "", InputProverFormatJSON, InputProverFormatProtobuf:
+	default:
+		return fmt.Errorf("unknown InputProverStorageFormat %q", c.InputProverStorageFormat)
+	}
+
+	if !common.IsHexAddress(c.SenderAddress) {
+		return fmt.Errorf("invalid SenderAddress %q", c.SenderAddress)
+	}
+
+	if c.ProverMaxConsecutiveFailures < 0 {
+		return fmt.Errorf("ProverMaxConsecutiveFailures must not be negative, got %d", c.ProverMaxConsecutiveFailures)
+	}
+	if c.MaxAggregationRetries < 0 {
+		return fmt.Errorf("MaxAggregationRetries must not be negative, got %d", c.MaxAggregationRetries)
+	}
+	if c.UnlockProofsMaxRetries < 0 {
+		return fmt.Errorf("UnlockProofsMaxRetries must not be negative, got %d", c.UnlockProofsMaxRetries)
+	}
+
+	if c.L1FailureThreshold < 0 {
+		return fmt.Errorf("L1FailureThreshold must not be negative, got %d", c.L1FailureThreshold)
+	}
+
+	for _, p := range c.BatchProofPriorities {
+		if p.FromBatch > p.ToBatch {
+			return fmt.Errorf("invalid BatchProofPriorities range %d-%d", p.FromBatch, p.ToBatch)
+		}
+	}
+
+	for _, r := range c.ExcludedBatchRanges {
+		if r.FromBatch > r.ToBatch {
+			return fmt.Errorf("invalid ExcludedBatchRanges range %d-%d", r.FromBatch, r.ToBatch)
+		}
+	}
+
+	if c.MaxLockedBatchesPerProver < 0 {
+		return fmt.Errorf("MaxLockedBatchesPerProver must not be negative, got %d", c.MaxLockedBatchesPerProver)
+	}
+
+	if c.MaxConnectedProvers < 0 {
+		return fmt.Errorf("MaxConnectedProvers must not be negative, got %d", c.MaxConnectedProvers)
+	}
+
+	if c.MaxLoggedProofLength < 0 {
+		return fmt.Errorf("MaxLoggedProofLength must not be negative, got %d", c.MaxLoggedProofLength)
+	}
+
+	return nil
+}
+
+// redactedValue replaces the sensitive values in the redacted configuration.
+const redactedValue = "<redacted>"
+
+// Redacted returns a copy of the configuration suitable to be shared in
+// support bundles, with the sender address, the listening address and the
+// local paths obscured. The values left empty are kept empty, so the features
+// they disable are still reported as disabled.
+func (c Config) Redacted() Config {
+	redact := func(s string) string {
+		if s == "" {
+			return s
+		}
+		return redactedValue
+	}
+	c.SenderAddress = redact(c.SenderAddress)
+	c.Host = redact(c.Host)
+	c.Port = 0
+	c.ProverProofDir = redact(c.ProverProofDir)
+	c.ExportVerifyCalldataDir = redact(c.ExportVerifyCalldataDir)
+	return c
 }
@@ -0,0 +1,180 @@
+package aggregator
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/0xPolygonHermez/zkevm-node/aggregator/mocks"
+	configTypes "github.com/0xPolygonHermez/zkevm-node/config/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfigValidate(t *testing.T) {
+	testCases := []struct {
+		name        string
+		modify      func(*Config)
+		expectedErr string
+	}{
+		{
+			name: "valid config",
+		},
+		{
+			name: "valid base profitability checker",
+			modify: func(c *Config) {
+				c.TxProfitabilityCheckerType = ProfitabilityBase
+				c.TxProfitabilityMinReward = TokenAmountWithDecimals{big.NewInt(1)}
+			},
+		},
+		{
+			name:        "zero retry time",
+			modify:      func(c *Config) { c.RetryTime = configTypes.NewDuration(0) },
+			expectedErr: "RetryTime must be positive, got 0s",
+		},
+		{
+			name:        "zero proof state polling interval",
+			modify:      func(c *Config) { c.ProofStatePollingInterval = configTypes.NewDuration(0) },
+			expectedErr: "ProofStatePollingInterval must be positive, got 0s",
+		},
+		{
+			name:        "negative verify proof interval",
+			modify:      func(c *Config) { c.VerifyProofInterval = configTypes.NewDuration(-time.Second) },
+			expectedErr: "VerifyProofInterval must not be negative, got -1s",
+		},
+		{
+			name:        "unknown profitability checker type",
+			modify:      func(c *Config) { c.TxProfitabilityCheckerType = "banana" },
+			expectedErr: `unknown TxProfitabilityCheckerType "banana"`,
+		},
+		{
+			name:        "base profitability checker without min reward",
+			modify:      func(c *Config) { c.TxProfitabilityCheckerType = ProfitabilityBase },
+			expectedErr: `TxProfitabilityMinReward is required for the "base" profitability checker`,
+		},
+		{
+			name: "valid file prover transport",
+			modify: func(c *Config) {
+				c.ProverTransport = ProverTransportFile
+				c.ProverProofDir = "/proofs"
+			},
+		},
+		{
+			name:        "unknown prover transport",
+			modify:      func(c *Config) { c.ProverTransport = "banana" },
+			expectedErr: `unknown ProverTransport "banana"`,
+		},
+		{
+			name:        "file prover transport without proof dir",
+			modify:      func(c *Config) { c.ProverTransport = ProverTransportFile },
+			expectedErr: `ProverProofDir is required for the "file" prover transport`,
+		},
+		{
+			name:        "empty sender address",
+			modify:      func(c *Config) { c.SenderAddress = "" },
+			expectedErr: `invalid SenderAddress ""`,
+		},
+		{
+			name: "invalid batch proof priority range",
+			modify: func(c *Config) {
+				c.BatchProofPriorities = []BatchProofPriority{{FromBatch: 10, ToBatch: 9, Priority: 1}}
+			},
+			expectedErr: "invalid BatchProofPriorities range 10-9",
+		},
+		{
+			name: "invalid excluded batch range",
+			modify: func(c *Config) {
+				c.ExcludedBatchRanges = []BatchRange{{FromBatch: 10, ToBatch: 9}}
+			},
+			expectedErr: "invalid ExcludedBatchRanges range 10-9",
+		},
+		{
+			name:        "unknown input prover storage format",
+			modify:      func(c *Config) { c.InputProverStorageFormat = "banana" },
+			expectedErr: `unknown InputProverStorageFormat "banana"`,
+		},
+		{
+			name:        "negative max connected provers",
+			modify:      func(c *Config) { c.MaxConnectedProvers = -1 },
+			expectedErr: "MaxConnectedProvers must not be negative, got -1",
+		},
+		{
+			name:        "negative max logged proof length",
+			modify:      func(c *Config) { c.MaxLoggedProofLength = -1 },
+			expectedErr: "MaxLoggedProofLength must not be negative, got -1",
+		},
+		{
+			name:        "negative max aggregation retries",
+			modify:      func(c *Config) { c.MaxAggregationRetries = -1 },
+			expectedErr: "MaxAggregationRetries must not be negative, got -1",
+		},
+		{
+			name:        "negative unlock proofs max retries",
+			modify:      func(c *Config) { c.UnlockProofsMaxRetries = -1 },
+			expectedErr: "UnlockProofsMaxRetries must not be negative, got -1",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := newTestConfig()
+			if tc.modify != nil {
+				tc.modify(&cfg)
+			}
+
+			err := cfg.Validate()
+
+			if tc.expectedErr != "" {
+				assert.EqualError(t, err, tc.expectedErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestNewInvalidConfig(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.TxProfitabilityCheckerType = "banana"
+
+	_, err := New(cfg, mocks.NewStateMock(t), mocks.NewEthTxManager(t), mocks.NewEtherman(t))
+
+	assert.EqualError(t, err, `invalid aggregator config, unknown TxProfitabilityCheckerType "banana"`)
+}
+
+func TestConfigRedacted(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.SenderAddress = "0x617b3a3528F9cDd6630fd3301B9c8911F7Bf063D"
+	cfg.Host = "10.0.0.1"
+	cfg.Port = 50081
+	cfg.ProverProofDir = "/var/lib/prover/proofs"
+	cfg.ExportVerifyCalldataDir = "/var/lib/aggregator/calldata"
+
+	redacted := cfg.Redacted()
+
+	assert.Equal(t, redactedValue, redacted.SenderAddress)
+	assert.Equal(t, redactedValue, redacted.Host)
+	assert.Zero(t, redacted.Port)
+	assert.Equal(t, redactedValue, redacted.ProverProofDir)
+	assert.Equal(t, redactedValue, redacted.ExportVerifyCalldataDir)
+	// the operational settings are kept
+	assert.Equal(t, cfg.VerifyProofInterval, redacted.VerifyProofInterval)
+	assert.Equal(t, cfg.TxProfitabilityCheckerType, redacted.TxProfitabilityCheckerType)
+	// the original configuration is not modified
+	assert.Equal(t, "0x617b3a3528F9cDd6630fd3301B9c8911F7Bf063D", cfg.SenderAddress)
+	assert.Equal(t, "/var/lib/prover/proofs", cfg.ProverProofDir)
+}
+
+func TestConfigRedactedEmptyValues(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.SenderAddress = ""
+	cfg.Host = ""
+	cfg.ProverProofDir = ""
+	cfg.ExportVerifyCalldataDir = ""
+
+	redacted := cfg.Redacted()
+
+	assert.Empty(t, redacted.SenderAddress)
+	assert.Empty(t, redacted.Host)
+	assert.Empty(t, redacted.ProverProofDir)
+	assert.Empty(t, redacted.ExportVerifyCalldataDir)
+}
@@ -0,0 +1,35 @@
+package aggregator
+
+import (
+	"sync"
+	"time"
+)
+
+// finalProofWait tracks since when the next batch to verify has been waiting
+// for a proof ready to be verified.
+type finalProofWait struct {
+	mu       sync.Mutex
+	batchNum uint64
+	since    time.Time
+}
+
+// elapsed returns how long the batch has been waiting, starting to count at
+// now if it was not the batch being waited for.
+func (w *finalProofWait) elapsed(batchNum uint64, now time.Time) time.Duration {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.since.IsZero() || w.batchNum != batchNum {
+		w.batchNum = batchNum
+		w.since = now
+	}
+	return now.Sub(w.since)
+}
+
+// reset stops the wait once a proof is ready to be verified.
+func (w *finalProofWait) reset() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.since = time.Time{}
+}
@@ -0,0 +1,24 @@
+package aggregator
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFinalProofWait(t *testing.T) {
+	assert := assert.New(t)
+	var w finalProofWait
+	now := time.Now()
+
+	assert.Zero(w.elapsed(23, now))
+	assert.Equal(time.Minute, w.elapsed(23, now.Add(time.Minute)))
+
+	// a new batch to verify restarts the wait
+	assert.Zero(w.elapsed(24, now.Add(2*time.Minute)))
+	assert.Equal(time.Minute, w.elapsed(24, now.Add(3*time.Minute)))
+
+	w.reset()
+	assert.Zero(w.elapsed(24, now.Add(4*time.Minute)))
+}
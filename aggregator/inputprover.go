@@ -0,0 +1,54 @@
+package aggregator
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+
+	"github.com/0xPolygonHermez/zkevm-node/aggregator/pb"
+	"google.golang.org/protobuf/proto"
+)
+
+// omittedInputProver is stored instead of a batch proof input exceeding the
+// MaxInputProverBytes, as a reference to the batch it was built for.
+type omittedInputProver struct {
+	BatchNumber uint64 `json:"omitted_input_prover_batch_num"`
+	Size        int    `json:"omitted_input_prover_bytes"`
+}
+
+// encodeInputProver serializes the input prover to be stored in the given
+// format, JSON by default. Both are deterministic, json.Marshal sorting the
+// keys of the Db and ContractsBytecode maps.
+func encodeInputProver(inputProver *pb.InputProver, format InputProverFormat) ([]byte, error) {
+	if format != InputProverFormatProtobuf {
+		return json.Marshal(inputProver)
+	}
+	b, err := proto.MarshalOptions{Deterministic: true}.Marshal(inputProver)
+	if err != nil {
+		return nil, err
+	}
+	encoded := make([]byte, base64.StdEncoding.EncodedLen(len(b)))
+	base64.StdEncoding.Encode(encoded, b)
+	return encoded, nil
+}
+
+// decodeInputProver deserializes a stored input prover in any of the storage
+// formats. JSON inputs always start with '{', which is not part of the base64
+// alphabet used for the protobuf ones.
+func decodeInputProver(s string) (*pb.InputProver, error) {
+	var inputProver pb.InputProver
+	if strings.HasPrefix(s, "{") {
+		if err := json.Unmarshal([]byte(s), &inputProver); err != nil {
+			return nil, err
+		}
+		return &inputProver, nil
+	}
+	b, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	if err := proto.Unmarshal(b, &inputProver); err != nil {
+		return nil, err
+	}
+	return &inputProver, nil
+}
@@ -0,0 +1,80 @@
+package aggregator
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/0xPolygonHermez/zkevm-node/aggregator/pb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestEncodeInputProverDeterministic(t *testing.T) {
+	newInputProver := func() *pb.InputProver {
+		return &pb.InputProver{
+			PublicInputs: &pb.PublicInputs{
+				OldStateRoot:   []byte("oldStateRoot"),
+				OldBatchNum:    22,
+				ChainId:        1000,
+				BatchL2Data:    []byte("batchL2Data"),
+				AggregatorAddr: "0x0000000000000000000000000000000000000001",
+			},
+			Db:                map[string]string{},
+			ContractsBytecode: map[string]string{},
+		}
+	}
+	keys := []string{"0x03", "0x01", "0x05", "0x02", "0x04"}
+	input1 := newInputProver()
+	for _, k := range keys {
+		input1.Db[k] = "value" + k
+		input1.ContractsBytecode[k] = "bytecode" + k
+	}
+	input2 := newInputProver()
+	for i := len(keys) - 1; i >= 0; i-- {
+		input2.Db[keys[i]] = "value" + keys[i]
+		input2.ContractsBytecode[keys[i]] = "bytecode" + keys[i]
+	}
+
+	b1, err := encodeInputProver(input1, InputProverFormatJSON)
+	require.NoError(t, err)
+	b2, err := encodeInputProver(input2, InputProverFormatJSON)
+	require.NoError(t, err)
+
+	assert.Equal(t, b1, b2)
+	var decoded pb.InputProver
+	require.NoError(t, json.Unmarshal(b1, &decoded))
+	assert.True(t, proto.Equal(input1, &decoded))
+}
+
+func TestEncodeDecodeInputProver(t *testing.T) {
+	inputProver := &pb.InputProver{
+		PublicInputs: &pb.PublicInputs{
+			OldStateRoot: []byte("oldStateRoot"),
+			OldBatchNum:  22,
+			ChainId:      1000,
+			BatchL2Data:  []byte("batchL2Data"),
+		},
+		Db:                map[string]string{"0x01": "value"},
+		ContractsBytecode: map[string]string{"0x02": "bytecode"},
+	}
+
+	for _, format := range []InputProverFormat{"", InputProverFormatJSON, InputProverFormatProtobuf} {
+		format := format
+		t.Run(string(format), func(t *testing.T) {
+			b, err := encodeInputProver(inputProver, format)
+			require.NoError(t, err)
+
+			decoded, err := decodeInputProver(string(b))
+			require.NoError(t, err)
+			assert.True(t, proto.Equal(inputProver, decoded))
+		})
+	}
+}
+
+func TestDecodeInputProverInvalid(t *testing.T) {
+	_, err := decodeInputProver("banana!")
+	assert.Error(t, err)
+	_, err = decodeInputProver("{banana")
+	assert.Error(t, err)
+}
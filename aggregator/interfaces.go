@@ -3,6 +3,7 @@ package aggregator
 import (
 	"context"
 	"math/big"
+	"time"
 
 	"github.com/0xPolygonHermez/zkevm-node/aggregator/pb"
 	ethmanTypes "github.com/0xPolygonHermez/zkevm-node/etherman/types"
@@ -38,7 +39,11 @@ type ethTxManager interface {
 // etherman contains the methods required to interact with ethereum
 type etherman interface {
 	GetLatestVerifiedBatchNum() (uint64, error)
+	GetLatestBlockNumber(ctx context.Context) (uint64, error)
 	BuildTrustedVerifyBatchesTxData(lastVerifiedBatch, newVerifiedBatch uint64, inputs *ethmanTypes.FinalProofInputs) (to *common.Address, data []byte, err error)
+	CheckTrustedVerifyBatches(ctx context.Context, sender common.Address, lastVerifiedBatch, newVerifiedBatch uint64, inputs *ethmanTypes.FinalProofInputs) error
+	GetBalance(ctx context.Context, account common.Address) (*big.Int, error)
+	GetSequencedBatchAccInputHash(ctx context.Context, batchNumber uint64) (common.Hash, error)
 }
 
 // aggregatorTxProfitabilityChecker interface for different profitability
@@ -52,9 +57,12 @@ type stateInterface interface {
 	BeginStateTransaction(ctx context.Context) (pgx.Tx, error)
 	CheckProofContainsCompleteSequences(ctx context.Context, proof *state.Proof, dbTx pgx.Tx) (bool, error)
 	GetLastVerifiedBatch(ctx context.Context, dbTx pgx.Tx) (*state.VerifiedBatch, error)
+	GetLastVirtualBatchNum(ctx context.Context, dbTx pgx.Tx) (uint64, error)
 	GetProofReadyToVerify(ctx context.Context, lastVerfiedBatchNumber uint64, dbTx pgx.Tx) (*state.Proof, error)
+	GetVirtualBatchTimestamp(ctx context.Context, batchNumber uint64, dbTx pgx.Tx) (time.Time, error)
 	GetVirtualBatchToProve(ctx context.Context, lastVerfiedBatchNumber uint64, dbTx pgx.Tx) (*state.Batch, error)
-	GetProofsToAggregate(ctx context.Context, dbTx pgx.Tx) (*state.Proof, *state.Proof, error)
+	GetVirtualBatchToProveInRange(ctx context.Context, lastVerfiedBatchNumber, fromBatchNumber, toBatchNumber uint64, dbTx pgx.Tx) (*state.Batch, error)
+	GetProofsToAggregate(ctx context.Context, fromBatchNumber, maxBatches uint64, dbTx pgx.Tx) (*state.Proof, *state.Proof, error)
 	GetBatchByNumber(ctx context.Context, batchNumber uint64, dbTx pgx.Tx) (*state.Batch, error)
 	AddGeneratedProof(ctx context.Context, proof *state.Proof, dbTx pgx.Tx) error
 	UpdateGeneratedProof(ctx context.Context, proof *state.Proof, dbTx pgx.Tx) error
@@ -62,4 +70,6 @@ type stateInterface interface {
 	DeleteUngeneratedProofs(ctx context.Context, dbTx pgx.Tx) error
 	CleanupGeneratedProofs(ctx context.Context, batchNumber uint64, dbTx pgx.Tx) error
 	CleanupLockedProofs(ctx context.Context, duration string, dbTx pgx.Tx) (int64, error)
+	GetGeneratingProofs(ctx context.Context, olderThan time.Duration, dbTx pgx.Tx) ([]*state.Proof, error)
+	ListProofs(ctx context.Context, fromBatch, fromBatchFinal, limit uint64, dbTx pgx.Tx) ([]*state.Proof, error)
 }
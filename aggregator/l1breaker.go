@@ -0,0 +1,64 @@
+package aggregator
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/0xPolygonHermez/zkevm-node/log"
+)
+
+// errL1BreakerOpen is returned instead of calling L1 while the breaker is
+// open after too many consecutive L1 failures.
+var errL1BreakerOpen = errors.New("L1 circuit breaker is open")
+
+// l1Breaker is a circuit breaker for the L1 calls of the aggregator. After
+// failureThreshold consecutive failures it opens for cooldown, short-circuiting
+// the calls. Once the cooldown is over the next call goes through: a success
+// closes the breaker and a failure opens it again.
+type l1Breaker struct {
+	failureThreshold int
+	cooldown         time.Duration
+
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+}
+
+func newL1Breaker(failureThreshold int, cooldown time.Duration) *l1Breaker {
+	return &l1Breaker{
+		failureThreshold: failureThreshold,
+		cooldown:         cooldown,
+	}
+}
+
+// call runs fn unless the breaker is open, in which case it returns
+// errL1BreakerOpen. The result of fn is recorded to open or close the breaker.
+// now provides the current time, usually the Now of the aggregator clock.
+func (b *l1Breaker) call(now func() time.Time, fn func() error) error {
+	if b.failureThreshold <= 0 {
+		return fn()
+	}
+
+	b.mu.Lock()
+	if now().Before(b.openUntil) {
+		b.mu.Unlock()
+		return errL1BreakerOpen
+	}
+	b.mu.Unlock()
+
+	err := fn()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err == nil {
+		b.failures = 0
+		return nil
+	}
+	b.failures++
+	if b.failures >= b.failureThreshold {
+		b.openUntil = now().Add(b.cooldown)
+		log.Warnf("L1 calls failed %d times in a row, short-circuiting them for %v", b.failures, b.cooldown)
+	}
+	return err
+}
@@ -0,0 +1,81 @@
+package aggregator
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/0xPolygonHermez/zkevm-node/aggregator/mocks"
+	configTypes "github.com/0xPolygonHermez/zkevm-node/config/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestL1Breaker(t *testing.T) {
+	assert := assert.New(t)
+	errBanana := errors.New("banana")
+	const cooldown = time.Minute
+	b := newL1Breaker(2, cooldown)
+	clk := newFakeClock(time.Now())
+	var calls int
+	fail := func() error { calls++; return errBanana }
+	succeed := func() error { calls++; return nil }
+
+	assert.ErrorIs(b.call(clk.Now, fail), errBanana)
+	assert.ErrorIs(b.call(clk.Now, fail), errBanana)
+	// the breaker is open, L1 is not called
+	assert.ErrorIs(b.call(clk.Now, succeed), errL1BreakerOpen)
+	assert.Equal(2, calls)
+
+	clk.Advance(cooldown)
+	// a success after the cooldown closes the breaker
+	assert.NoError(b.call(clk.Now, succeed))
+	assert.Equal(3, calls)
+	assert.ErrorIs(b.call(clk.Now, fail), errBanana)
+	assert.NoError(b.call(clk.Now, succeed))
+	assert.Equal(5, calls)
+
+	// a failure after the cooldown opens it again
+	assert.ErrorIs(b.call(clk.Now, fail), errBanana)
+	assert.ErrorIs(b.call(clk.Now, fail), errBanana)
+	clk.Advance(cooldown)
+	assert.ErrorIs(b.call(clk.Now, fail), errBanana)
+	assert.ErrorIs(b.call(clk.Now, succeed), errL1BreakerOpen)
+	assert.Equal(8, calls)
+}
+
+func TestL1BreakerDisabled(t *testing.T) {
+	errBanana := errors.New("banana")
+	b := newL1Breaker(0, time.Minute)
+	clk := newFakeClock(time.Now())
+
+	for i := 0; i < 5; i++ {
+		assert.ErrorIs(t, b.call(clk.Now, func() error { return errBanana }), errBanana)
+	}
+	assert.NoError(t, b.call(clk.Now, func() error { return nil }))
+}
+
+func TestL1BreakerAggregatorCalls(t *testing.T) {
+	errBanana := errors.New("banana")
+	cfg := newTestConfig()
+	cfg.L1FailureThreshold = 1
+	cfg.L1BreakerCooldown = configTypes.NewDuration(time.Minute)
+	cfg.MinSenderBalance = TokenAmountWithDecimals{big.NewInt(1000)}
+	etherman := mocks.NewEtherman(t)
+	a, err := New(cfg, mocks.NewStateMock(t), mocks.NewEthTxManager(t), etherman)
+	require.NoError(t, err)
+	clk := newFakeClock(time.Now())
+	a.clock = clk
+	etherman.On("GetBalance", mock.Anything, mock.Anything).Return(nil, errBanana).Once()
+
+	assert.ErrorIs(t, a.checkMinSenderBalance(context.Background()), errBanana)
+	// the breaker is open, L1 is not called
+	assert.ErrorIs(t, a.checkMinSenderBalance(context.Background()), errL1BreakerOpen)
+
+	clk.Advance(time.Minute)
+	etherman.On("GetBalance", mock.Anything, mock.Anything).Return(big.NewInt(1000), nil).Once()
+	assert.NoError(t, a.checkMinSenderBalance(context.Background()))
+}
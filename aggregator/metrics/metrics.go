@@ -1,14 +1,40 @@
 package metrics
 
 import (
+	"time"
+
 	"github.com/0xPolygonHermez/zkevm-node/metrics"
 	"github.com/prometheus/client_golang/prometheus"
 )
 
 const (
-	prefix                      = "aggregator_"
-	currentConnectedProversName = prefix + "current_connected_provers"
-	currentWorkingProversName   = prefix + "current_working_provers"
+	prefix                       = "aggregator_"
+	currentConnectedProversName  = prefix + "current_connected_provers"
+	currentWorkingProversName    = prefix + "current_working_provers"
+	blocklistedProversName       = prefix + "blocklisted_provers"
+	oldestUnprovenBatchAgeName   = prefix + "oldest_unproven_batch_age_seconds"
+	stateAheadOfL1Name           = prefix + "state_ahead_of_l1"
+	oversizedInputProversName    = prefix + "oversized_input_provers"
+	finalProofGapName            = prefix + "final_proof_gap_batches"
+	finalProofWaitName           = prefix + "final_proof_wait_seconds"
+	finalProofRootMismatchesName = prefix + "final_proof_root_mismatches"
+	provingSLABreachesName       = prefix + "proving_sla_breaches"
+	proverProofsName             = prefix + "prover_proofs_total"
+	quarantinedAggregationsName  = prefix + "quarantined_aggregations"
+
+	proverLabelName      = "prover"
+	proofResultLabelName = "result"
+)
+
+// ProofResultLabel represents the possible values for the
+// `aggregator_prover_proofs_total` metric `result` label.
+type ProofResultLabel string
+
+const (
+	// ProofResultLabelSuccess represents a proof generated by the prover
+	ProofResultLabelSuccess ProofResultLabel = "success"
+	// ProofResultLabelFailure represents a proof the prover failed to generate
+	ProofResultLabelFailure ProofResultLabel = "failure"
 )
 
 // Register the metrics for the sequencer package.
@@ -22,9 +48,60 @@ func Register() {
 			Name: currentWorkingProversName,
 			Help: "[AGGREGATOR] current working provers",
 		},
+		{
+			Name: oldestUnprovenBatchAgeName,
+			Help: "[AGGREGATOR] age in seconds of the oldest virtual batch pending to be proved",
+		},
+		{
+			Name: finalProofGapName,
+			Help: "[AGGREGATOR] number of batches between the last final proof built and the last verified batch",
+		},
+		{
+			Name: finalProofWaitName,
+			Help: "[AGGREGATOR] time in seconds the next batch to verify has been waiting for a proof ready to verify",
+		},
+	}
+
+	counters := []prometheus.CounterOpts{
+		{
+			Name: blocklistedProversName,
+			Help: "[AGGREGATOR] total count of provers blocklisted after repeated failures",
+		},
+		{
+			Name: stateAheadOfL1Name,
+			Help: "[AGGREGATOR] total count of sync checks with the state verified batch ahead of L1",
+		},
+		{
+			Name: oversizedInputProversName,
+			Help: "[AGGREGATOR] total count of batch proof inputs exceeding the maximum size stored",
+		},
+		{
+			Name: finalProofRootMismatchesName,
+			Help: "[AGGREGATOR] total count of final proofs discarded for roots not matching the batch",
+		},
+		{
+			Name: provingSLABreachesName,
+			Help: "[AGGREGATOR] total count of verified batch ranges sequenced longer than the proving SLA before",
+		},
+		{
+			Name: quarantinedAggregationsName,
+			Help: "[AGGREGATOR] total count of proof pairs quarantined after repeated aggregation failures",
+		},
+	}
+
+	counterVecs := []metrics.CounterVecOpts{
+		{
+			CounterOpts: prometheus.CounterOpts{
+				Name: proverProofsName,
+				Help: "[AGGREGATOR] total count of batch, aggregated and final proofs requested to each prover by result",
+			},
+			Labels: []string{proverLabelName, proofResultLabelName},
+		},
 	}
 
 	metrics.RegisterGauges(gauges...)
+	metrics.RegisterCounters(counters...)
+	metrics.RegisterCounterVecs(counterVecs...)
 }
 
 // ConnectedProver increments the gauge for the current number of connected
@@ -49,3 +126,65 @@ func WorkingProver() {
 func IdlingProver() {
 	metrics.GaugeDec(currentWorkingProversName)
 }
+
+// BlocklistedProver increments the counter for the number of times a prover
+// has been blocklisted.
+func BlocklistedProver() {
+	metrics.CounterInc(blocklistedProversName)
+}
+
+// StateAheadOfL1 increments the counter for the number of sync checks that
+// found the state verified batch ahead of the L1 verified batch.
+func StateAheadOfL1() {
+	metrics.CounterInc(stateAheadOfL1Name)
+}
+
+// OversizedInputProver increments the counter for the number of batch proof
+// inputs exceeding the maximum size stored.
+func OversizedInputProver() {
+	metrics.CounterInc(oversizedInputProversName)
+}
+
+// FinalProofRootMismatch increments the counter for the number of final
+// proofs discarded for roots not matching the batch being verified.
+func FinalProofRootMismatch() {
+	metrics.CounterInc(finalProofRootMismatchesName)
+}
+
+// ProvingSLABreach increments the counter for the number of verified batch
+// ranges that exceeded the proving SLA.
+func ProvingSLABreach() {
+	metrics.CounterInc(provingSLABreachesName)
+}
+
+// QuarantinedAggregation increments the counter for the number of proof
+// pairs quarantined after repeated aggregation failures.
+func QuarantinedAggregation() {
+	metrics.CounterInc(quarantinedAggregationsName)
+}
+
+// ProverProof increments the counter for the number of proofs requested to
+// the given prover with the given result.
+func ProverProof(prover string, result ProofResultLabel) {
+	if cv, ok := metrics.CounterVec(proverProofsName); ok {
+		cv.WithLabelValues(prover, string(result)).Inc()
+	}
+}
+
+// OldestUnprovenBatchAge sets the gauge for the age of the oldest virtual
+// batch pending to be proved.
+func OldestUnprovenBatchAge(age time.Duration) {
+	metrics.GaugeSet(oldestUnprovenBatchAgeName, age.Seconds())
+}
+
+// FinalProofGap sets the gauge for the number of batches between the last
+// final proof built and the last verified batch.
+func FinalProofGap(gap uint64) {
+	metrics.GaugeSet(finalProofGapName, float64(gap))
+}
+
+// FinalProofWait sets the gauge for the time the next batch to verify has been
+// waiting for a proof ready to verify.
+func FinalProofWait(wait time.Duration) {
+	metrics.GaugeSet(finalProofWaitName, wait.Seconds())
+}
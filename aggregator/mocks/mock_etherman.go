@@ -3,6 +3,10 @@
 package mocks
 
 import (
+	big "math/big"
+
+	context "context"
+
 	common "github.com/ethereum/go-ethereum/common"
 	mock "github.com/stretchr/testify/mock"
 
@@ -49,6 +53,70 @@ func (_m *Etherman) BuildTrustedVerifyBatchesTxData(lastVerifiedBatch uint64, ne
 	return r0, r1, r2
 }
 
+// CheckTrustedVerifyBatches provides a mock function with given fields: ctx, sender, lastVerifiedBatch, newVerifiedBatch, inputs
+func (_m *Etherman) CheckTrustedVerifyBatches(ctx context.Context, sender common.Address, lastVerifiedBatch uint64, newVerifiedBatch uint64, inputs *types.FinalProofInputs) error {
+	ret := _m.Called(ctx, sender, lastVerifiedBatch, newVerifiedBatch, inputs)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, common.Address, uint64, uint64, *types.FinalProofInputs) error); ok {
+		r0 = rf(ctx, sender, lastVerifiedBatch, newVerifiedBatch, inputs)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// GetBalance provides a mock function with given fields: ctx, account
+func (_m *Etherman) GetBalance(ctx context.Context, account common.Address) (*big.Int, error) {
+	ret := _m.Called(ctx, account)
+
+	var r0 *big.Int
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, common.Address) (*big.Int, error)); ok {
+		return rf(ctx, account)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, common.Address) *big.Int); ok {
+		r0 = rf(ctx, account)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*big.Int)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, common.Address) error); ok {
+		r1 = rf(ctx, account)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetLatestBlockNumber provides a mock function with given fields: ctx
+func (_m *Etherman) GetLatestBlockNumber(ctx context.Context) (uint64, error) {
+	ret := _m.Called(ctx)
+
+	var r0 uint64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) (uint64, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) uint64); ok {
+		r0 = rf(ctx)
+	} else {
+		r0 = ret.Get(0).(uint64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetLatestVerifiedBatchNum provides a mock function with given fields:
 func (_m *Etherman) GetLatestVerifiedBatchNum() (uint64, error) {
 	ret := _m.Called()
@@ -73,6 +141,32 @@ func (_m *Etherman) GetLatestVerifiedBatchNum() (uint64, error) {
 	return r0, r1
 }
 
+// GetSequencedBatchAccInputHash provides a mock function with given fields: ctx, batchNumber
+func (_m *Etherman) GetSequencedBatchAccInputHash(ctx context.Context, batchNumber uint64) (common.Hash, error) {
+	ret := _m.Called(ctx, batchNumber)
+
+	var r0 common.Hash
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, uint64) (common.Hash, error)); ok {
+		return rf(ctx, batchNumber)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, uint64) common.Hash); ok {
+		r0 = rf(ctx, batchNumber)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(common.Hash)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, uint64) error); ok {
+		r1 = rf(ctx, batchNumber)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 type mockConstructorTestingTNewEtherman interface {
 	mock.TestingT
 	Cleanup(func())
@@ -9,6 +9,8 @@ import (
 	mock "github.com/stretchr/testify/mock"
 
 	state "github.com/0xPolygonHermez/zkevm-node/state"
+
+	time "time"
 )
 
 // StateMock is an autogenerated mock type for the stateInterface type
@@ -172,6 +174,56 @@ func (_m *StateMock) GetBatchByNumber(ctx context.Context, batchNumber uint64, d
 	return r0, r1
 }
 
+// GetGeneratingProofs provides a mock function with given fields: ctx, olderThan, dbTx
+func (_m *StateMock) GetGeneratingProofs(ctx context.Context, olderThan time.Duration, dbTx pgx.Tx) ([]*state.Proof, error) {
+	ret := _m.Called(ctx, olderThan, dbTx)
+
+	var r0 []*state.Proof
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, time.Duration, pgx.Tx) ([]*state.Proof, error)); ok {
+		return rf(ctx, olderThan, dbTx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, time.Duration, pgx.Tx) []*state.Proof); ok {
+		r0 = rf(ctx, olderThan, dbTx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*state.Proof)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, time.Duration, pgx.Tx) error); ok {
+		r1 = rf(ctx, olderThan, dbTx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetLastVirtualBatchNum provides a mock function with given fields: ctx, dbTx
+func (_m *StateMock) GetLastVirtualBatchNum(ctx context.Context, dbTx pgx.Tx) (uint64, error) {
+	ret := _m.Called(ctx, dbTx)
+
+	var r0 uint64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, pgx.Tx) (uint64, error)); ok {
+		return rf(ctx, dbTx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, pgx.Tx) uint64); ok {
+		r0 = rf(ctx, dbTx)
+	} else {
+		r0 = ret.Get(0).(uint64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, pgx.Tx) error); ok {
+		r1 = rf(ctx, dbTx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetLastVerifiedBatch provides a mock function with given fields: ctx, dbTx
 func (_m *StateMock) GetLastVerifiedBatch(ctx context.Context, dbTx pgx.Tx) (*state.VerifiedBatch, error) {
 	ret := _m.Called(ctx, dbTx)
@@ -224,34 +276,34 @@ func (_m *StateMock) GetProofReadyToVerify(ctx context.Context, lastVerfiedBatch
 	return r0, r1
 }
 
-// GetProofsToAggregate provides a mock function with given fields: ctx, dbTx
-func (_m *StateMock) GetProofsToAggregate(ctx context.Context, dbTx pgx.Tx) (*state.Proof, *state.Proof, error) {
-	ret := _m.Called(ctx, dbTx)
+// GetProofsToAggregate provides a mock function with given fields: ctx, fromBatchNumber, maxBatches, dbTx
+func (_m *StateMock) GetProofsToAggregate(ctx context.Context, fromBatchNumber uint64, maxBatches uint64, dbTx pgx.Tx) (*state.Proof, *state.Proof, error) {
+	ret := _m.Called(ctx, fromBatchNumber, maxBatches, dbTx)
 
 	var r0 *state.Proof
 	var r1 *state.Proof
 	var r2 error
-	if rf, ok := ret.Get(0).(func(context.Context, pgx.Tx) (*state.Proof, *state.Proof, error)); ok {
-		return rf(ctx, dbTx)
+	if rf, ok := ret.Get(0).(func(context.Context, uint64, uint64, pgx.Tx) (*state.Proof, *state.Proof, error)); ok {
+		return rf(ctx, fromBatchNumber, maxBatches, dbTx)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, pgx.Tx) *state.Proof); ok {
-		r0 = rf(ctx, dbTx)
+	if rf, ok := ret.Get(0).(func(context.Context, uint64, uint64, pgx.Tx) *state.Proof); ok {
+		r0 = rf(ctx, fromBatchNumber, maxBatches, dbTx)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(*state.Proof)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, pgx.Tx) *state.Proof); ok {
-		r1 = rf(ctx, dbTx)
+	if rf, ok := ret.Get(1).(func(context.Context, uint64, uint64, pgx.Tx) *state.Proof); ok {
+		r1 = rf(ctx, fromBatchNumber, maxBatches, dbTx)
 	} else {
 		if ret.Get(1) != nil {
 			r1 = ret.Get(1).(*state.Proof)
 		}
 	}
 
-	if rf, ok := ret.Get(2).(func(context.Context, pgx.Tx) error); ok {
-		r2 = rf(ctx, dbTx)
+	if rf, ok := ret.Get(2).(func(context.Context, uint64, uint64, pgx.Tx) error); ok {
+		r2 = rf(ctx, fromBatchNumber, maxBatches, dbTx)
 	} else {
 		r2 = ret.Error(2)
 	}
@@ -259,6 +311,30 @@ func (_m *StateMock) GetProofsToAggregate(ctx context.Context, dbTx pgx.Tx) (*st
 	return r0, r1, r2
 }
 
+// GetVirtualBatchTimestamp provides a mock function with given fields: ctx, batchNumber, dbTx
+func (_m *StateMock) GetVirtualBatchTimestamp(ctx context.Context, batchNumber uint64, dbTx pgx.Tx) (time.Time, error) {
+	ret := _m.Called(ctx, batchNumber, dbTx)
+
+	var r0 time.Time
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, uint64, pgx.Tx) (time.Time, error)); ok {
+		return rf(ctx, batchNumber, dbTx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, uint64, pgx.Tx) time.Time); ok {
+		r0 = rf(ctx, batchNumber, dbTx)
+	} else {
+		r0 = ret.Get(0).(time.Time)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, uint64, pgx.Tx) error); ok {
+		r1 = rf(ctx, batchNumber, dbTx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetVirtualBatchToProve provides a mock function with given fields: ctx, lastVerfiedBatchNumber, dbTx
 func (_m *StateMock) GetVirtualBatchToProve(ctx context.Context, lastVerfiedBatchNumber uint64, dbTx pgx.Tx) (*state.Batch, error) {
 	ret := _m.Called(ctx, lastVerfiedBatchNumber, dbTx)
@@ -285,6 +361,58 @@ func (_m *StateMock) GetVirtualBatchToProve(ctx context.Context, lastVerfiedBatc
 	return r0, r1
 }
 
+// GetVirtualBatchToProveInRange provides a mock function with given fields: ctx, lastVerfiedBatchNumber, fromBatchNumber, toBatchNumber, dbTx
+func (_m *StateMock) GetVirtualBatchToProveInRange(ctx context.Context, lastVerfiedBatchNumber uint64, fromBatchNumber uint64, toBatchNumber uint64, dbTx pgx.Tx) (*state.Batch, error) {
+	ret := _m.Called(ctx, lastVerfiedBatchNumber, fromBatchNumber, toBatchNumber, dbTx)
+
+	var r0 *state.Batch
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, uint64, uint64, uint64, pgx.Tx) (*state.Batch, error)); ok {
+		return rf(ctx, lastVerfiedBatchNumber, fromBatchNumber, toBatchNumber, dbTx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, uint64, uint64, uint64, pgx.Tx) *state.Batch); ok {
+		r0 = rf(ctx, lastVerfiedBatchNumber, fromBatchNumber, toBatchNumber, dbTx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*state.Batch)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, uint64, uint64, uint64, pgx.Tx) error); ok {
+		r1 = rf(ctx, lastVerfiedBatchNumber, fromBatchNumber, toBatchNumber, dbTx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListProofs provides a mock function with given fields: ctx, fromBatch, fromBatchFinal, limit, dbTx
+func (_m *StateMock) ListProofs(ctx context.Context, fromBatch uint64, fromBatchFinal uint64, limit uint64, dbTx pgx.Tx) ([]*state.Proof, error) {
+	ret := _m.Called(ctx, fromBatch, fromBatchFinal, limit, dbTx)
+
+	var r0 []*state.Proof
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, uint64, uint64, uint64, pgx.Tx) ([]*state.Proof, error)); ok {
+		return rf(ctx, fromBatch, fromBatchFinal, limit, dbTx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, uint64, uint64, uint64, pgx.Tx) []*state.Proof); ok {
+		r0 = rf(ctx, fromBatch, fromBatchFinal, limit, dbTx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*state.Proof)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, uint64, uint64, uint64, pgx.Tx) error); ok {
+		r1 = rf(ctx, fromBatch, fromBatchFinal, limit, dbTx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // UpdateGeneratedProof provides a mock function with given fields: ctx, proof, dbTx
 func (_m *StateMock) UpdateGeneratedProof(ctx context.Context, proof *state.Proof, dbTx pgx.Tx) error {
 	ret := _m.Called(ctx, proof, dbTx)
@@ -0,0 +1,107 @@
+package aggregator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/0xPolygonHermez/zkevm-node/state"
+)
+
+// ProofTreeIssueType is the kind of inconsistency found auditing the stored
+// proofs.
+type ProofTreeIssueType string
+
+const (
+	// ProofTreeGap means that there is a range of batches not covered by any
+	// proof between the last verified batch and the highest stored proof.
+	ProofTreeGap ProofTreeIssueType = "gap"
+	// ProofTreeOverlap means that a range of batches is covered by more than
+	// one proof.
+	ProofTreeOverlap ProofTreeIssueType = "overlap"
+	// ProofTreeOrphan means that a proof covers batches that are no longer
+	// virtual batches in the state, deleted by a reorg after being proved.
+	ProofTreeOrphan ProofTreeIssueType = "orphan"
+)
+
+// ProofTreeIssue describes an inconsistency found in the stored proofs for
+// the batch range [BatchNumber, BatchNumberFinal].
+type ProofTreeIssue struct {
+	Type             ProofTreeIssueType
+	BatchNumber      uint64
+	BatchNumberFinal uint64
+}
+
+// String returns a human readable representation of the issue.
+func (i ProofTreeIssue) String() string {
+	return fmt.Sprintf("%s %d-%d", i.Type, i.BatchNumber, i.BatchNumberFinal)
+}
+
+// AuditProofTree walks all the stored proofs looking for gaps and overlaps in
+// the batch ranges they cover, starting from the batch following the last
+// verified one, and for orphan proofs covering batches no longer in the
+// state. It only reads from the state and returns the issues found.
+func (a *Aggregator) AuditProofTree(ctx context.Context) ([]ProofTreeIssue, error) {
+	var lastVerifiedBatchNum uint64
+	lastVerifiedBatch, err := a.State.GetLastVerifiedBatch(ctx, nil)
+	if err != nil && !errors.Is(err, state.ErrNotFound) {
+		return nil, fmt.Errorf("failed to get last verified batch, %w", err)
+	}
+	if lastVerifiedBatch != nil {
+		lastVerifiedBatchNum = lastVerifiedBatch.BatchNumber
+	}
+
+	lastVirtualBatchNum, err := a.State.GetLastVirtualBatchNum(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get last virtual batch num, %w", err)
+	}
+
+	issues := []ProofTreeIssue{}
+	nextBatchNum := lastVerifiedBatchNum + 1
+	// highest batch number covered so far by the walked proofs
+	var coveredBatchNum *uint64
+	err = a.forEachStoredProof(ctx, func(proof *state.Proof) error {
+		if proof.BatchNumberFinal < lastVerifiedBatchNum+1 {
+			// already verified, pending to be cleaned up
+			return nil
+		}
+
+		if proof.BatchNumberFinal > lastVirtualBatchNum {
+			issues = append(issues, ProofTreeIssue{
+				Type:             ProofTreeOrphan,
+				BatchNumber:      proof.BatchNumber,
+				BatchNumberFinal: proof.BatchNumberFinal,
+			})
+		}
+
+		if coveredBatchNum != nil && proof.BatchNumber <= *coveredBatchNum {
+			overlapFinal := proof.BatchNumberFinal
+			if *coveredBatchNum < overlapFinal {
+				overlapFinal = *coveredBatchNum
+			}
+			issues = append(issues, ProofTreeIssue{
+				Type:             ProofTreeOverlap,
+				BatchNumber:      proof.BatchNumber,
+				BatchNumberFinal: overlapFinal,
+			})
+		} else if proof.BatchNumber > nextBatchNum {
+			issues = append(issues, ProofTreeIssue{
+				Type:             ProofTreeGap,
+				BatchNumber:      nextBatchNum,
+				BatchNumberFinal: proof.BatchNumber - 1,
+			})
+		}
+
+		if proof.BatchNumberFinal+1 > nextBatchNum {
+			nextBatchNum = proof.BatchNumberFinal + 1
+			covered := proof.BatchNumberFinal
+			coveredBatchNum = &covered
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk the stored proofs, %w", err)
+	}
+
+	return issues, nil
+}
@@ -0,0 +1,110 @@
+package aggregator
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/0xPolygonHermez/zkevm-node/aggregator/mocks"
+	"github.com/0xPolygonHermez/zkevm-node/state"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuditProofTree(t *testing.T) {
+	errBanana := errors.New("banana")
+	lastVerifiedBatch := state.VerifiedBatch{BatchNumber: 10}
+	lastVirtualBatchNum := uint64(20)
+
+	testCases := []struct {
+		name           string
+		setup          func(mox)
+		expectedIssues []ProofTreeIssue
+		expectedErr    error
+	}{
+		{
+			name: "contiguous proofs",
+			setup: func(m mox) {
+				m.stateMock.On("GetLastVerifiedBatch", mock.Anything, nil).Return(&lastVerifiedBatch, nil).Once()
+				m.stateMock.On("GetLastVirtualBatchNum", mock.Anything, nil).Return(lastVirtualBatchNum, nil).Once()
+				m.stateMock.On("ListProofs", mock.Anything, uint64(0), uint64(0), uint64(listProofsPageSize), nil).Return([]*state.Proof{
+					{BatchNumber: 5, BatchNumberFinal: 10},
+					{BatchNumber: 11, BatchNumberFinal: 12},
+					{BatchNumber: 13, BatchNumberFinal: 13},
+				}, nil).Once()
+			},
+			expectedIssues: []ProofTreeIssue{},
+		},
+		{
+			name: "gap and overlap reported",
+			setup: func(m mox) {
+				m.stateMock.On("GetLastVerifiedBatch", mock.Anything, nil).Return(&lastVerifiedBatch, nil).Once()
+				m.stateMock.On("GetLastVirtualBatchNum", mock.Anything, nil).Return(lastVirtualBatchNum, nil).Once()
+				m.stateMock.On("ListProofs", mock.Anything, uint64(0), uint64(0), uint64(listProofsPageSize), nil).Return([]*state.Proof{
+					{BatchNumber: 11, BatchNumberFinal: 12},
+					{BatchNumber: 15, BatchNumberFinal: 18},
+					{BatchNumber: 17, BatchNumberFinal: 20},
+				}, nil).Once()
+			},
+			expectedIssues: []ProofTreeIssue{
+				{Type: ProofTreeGap, BatchNumber: 13, BatchNumberFinal: 14},
+				{Type: ProofTreeOverlap, BatchNumber: 17, BatchNumberFinal: 18},
+			},
+		},
+		{
+			name: "orphan proof reported",
+			setup: func(m mox) {
+				m.stateMock.On("GetLastVerifiedBatch", mock.Anything, nil).Return(&lastVerifiedBatch, nil).Once()
+				m.stateMock.On("GetLastVirtualBatchNum", mock.Anything, nil).Return(uint64(14), nil).Once()
+				m.stateMock.On("ListProofs", mock.Anything, uint64(0), uint64(0), uint64(listProofsPageSize), nil).Return([]*state.Proof{
+					{BatchNumber: 11, BatchNumberFinal: 12},
+					{BatchNumber: 13, BatchNumberFinal: 16},
+				}, nil).Once()
+			},
+			expectedIssues: []ProofTreeIssue{
+				{Type: ProofTreeOrphan, BatchNumber: 13, BatchNumberFinal: 16},
+			},
+		},
+		{
+			name: "gap right after last verified batch",
+			setup: func(m mox) {
+				m.stateMock.On("GetLastVerifiedBatch", mock.Anything, nil).Return(nil, state.ErrNotFound).Once()
+				m.stateMock.On("GetLastVirtualBatchNum", mock.Anything, nil).Return(lastVirtualBatchNum, nil).Once()
+				m.stateMock.On("ListProofs", mock.Anything, uint64(0), uint64(0), uint64(listProofsPageSize), nil).Return([]*state.Proof{
+					{BatchNumber: 3, BatchNumberFinal: 4},
+				}, nil).Once()
+			},
+			expectedIssues: []ProofTreeIssue{
+				{Type: ProofTreeGap, BatchNumber: 1, BatchNumberFinal: 2},
+			},
+		},
+		{
+			name: "state error",
+			setup: func(m mox) {
+				m.stateMock.On("GetLastVerifiedBatch", mock.Anything, nil).Return(&lastVerifiedBatch, nil).Once()
+				m.stateMock.On("GetLastVirtualBatchNum", mock.Anything, nil).Return(lastVirtualBatchNum, nil).Once()
+				m.stateMock.On("ListProofs", mock.Anything, uint64(0), uint64(0), uint64(listProofsPageSize), nil).Return(nil, errBanana).Once()
+			},
+			expectedErr: errBanana,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			stateMock := mocks.NewStateMock(t)
+			a, err := New(newTestConfig(), stateMock, mocks.NewEthTxManager(t), mocks.NewEtherman(t))
+			require.NoError(t, err)
+			tc.setup(mox{stateMock: stateMock})
+
+			issues, err := a.AuditProofTree(context.Background())
+
+			if tc.expectedErr != nil {
+				assert.ErrorIs(t, err, tc.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedIssues, issues)
+		})
+	}
+}
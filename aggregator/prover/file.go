@@ -0,0 +1,86 @@
+package prover
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/0xPolygonHermez/zkevm-node/aggregator/metrics"
+	"github.com/0xPolygonHermez/zkevm-node/aggregator/pb"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// FileProver is a Prover that receives the proof requests over the stream
+// but reads the generated proofs from a directory shared with the prover,
+// for provers that can't return them over gRPC. The prover must write each
+// proof atomically to a file named by the proof ID: the recursive proofs as
+// is and the final proofs as the JSON encoding of pb.FinalProof.
+type FileProver struct {
+	*Prover
+	proofDir string
+}
+
+// NewFileProver returns a new FileProver reading the proofs of the given
+// prover from proofDir.
+func NewFileProver(p *Prover, proofDir string) *FileProver {
+	return &FileProver{
+		Prover:   p,
+		proofDir: proofDir,
+	}
+}
+
+// WaitRecursiveProof waits for the recursive proof to be written by the
+// prover and returns it.
+func (p *FileProver) WaitRecursiveProof(ctx context.Context, proofID string) (string, error) {
+	b, err := p.waitProofFile(ctx, proofID)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// WaitFinalProof waits for the final proof to be written by the prover and
+// returns it.
+func (p *FileProver) WaitFinalProof(ctx context.Context, proofID string) (*pb.FinalProof, error) {
+	b, err := p.waitProofFile(ctx, proofID)
+	if err != nil {
+		return nil, err
+	}
+	var finalProof pb.FinalProof
+	if err := protojson.Unmarshal(b, &finalProof); err != nil {
+		return nil, fmt.Errorf("failed to decode final proof with ID %s, %w", proofID, err)
+	}
+	return &finalProof, nil
+}
+
+// waitProofFile polls the proof directory until the file of the proof
+// exists and returns its content.
+func (p *FileProver) waitProofFile(ctx context.Context, proofID string) ([]byte, error) {
+	defer metrics.IdlingProver()
+
+	if proofID == "" || filepath.Base(proofID) != proofID {
+		return nil, fmt.Errorf("invalid proof ID %q for a proof file", proofID)
+	}
+	path := filepath.Join(p.proofDir, proofID)
+
+	waitingSince := time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		default:
+			b, err := os.ReadFile(path)
+			if errors.Is(err, os.ErrNotExist) {
+				time.Sleep(pollingInterval(p.proofStatePollingInterval.Duration, time.Since(waitingSince)))
+				continue
+			}
+			if err != nil {
+				return nil, fmt.Errorf("failed to read proof file %s, %w", path, err)
+			}
+			return b, nil
+		}
+	}
+}
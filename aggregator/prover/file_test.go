@@ -0,0 +1,55 @@
+package prover
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/0xPolygonHermez/zkevm-node/aggregator/pb"
+	"github.com/0xPolygonHermez/zkevm-node/config/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+func TestFileProverWaitRecursiveProof(t *testing.T) {
+	dir := t.TempDir()
+	p := NewFileProver(&Prover{proofStatePollingInterval: types.NewDuration(time.Millisecond)}, dir)
+	time.AfterFunc(50*time.Millisecond, func() {
+		_ = os.WriteFile(filepath.Join(dir, "proofId"), []byte("recursiveProof"), 0600)
+	})
+
+	proof, err := p.WaitRecursiveProof(context.Background(), "proofId")
+
+	require.NoError(t, err)
+	assert.Equal(t, "recursiveProof", proof)
+}
+
+func TestFileProverWaitFinalProof(t *testing.T) {
+	dir := t.TempDir()
+	p := NewFileProver(&Prover{proofStatePollingInterval: types.NewDuration(time.Millisecond)}, dir)
+	expected := &pb.FinalProof{Proof: "finalProof", Public: &pb.PublicInputsExtended{NewStateRoot: []byte("newStateRoot")}}
+	b, err := protojson.Marshal(expected)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "proofId"), b, 0600))
+
+	proof, err := p.WaitFinalProof(context.Background(), "proofId")
+
+	require.NoError(t, err)
+	assert.Equal(t, expected.Proof, proof.Proof)
+	assert.Equal(t, expected.Public.NewStateRoot, proof.Public.NewStateRoot)
+}
+
+func TestFileProverWaitProofFile(t *testing.T) {
+	p := NewFileProver(&Prover{proofStatePollingInterval: types.NewDuration(time.Millisecond)}, t.TempDir())
+
+	_, err := p.WaitRecursiveProof(context.Background(), "../proofId")
+	assert.EqualError(t, err, `invalid proof ID "../proofId" for a proof file`)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err = p.WaitRecursiveProof(ctx, "missing")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"math/rand"
 	"net"
 	"time"
 
@@ -21,6 +22,20 @@ var (
 	ErrUnspecified          = errors.New("Prover returned an UNSPECIFIED response")  //nolint:revive
 	ErrUnknown              = errors.New("Prover returned an unknown response")      //nolint:revive
 	ErrProofCanceled        = errors.New("Proof has been canceled")                  //nolint:revive
+	ErrProofIDMismatch      = errors.New("Prover returned a proof for another ID")   //nolint:revive
+)
+
+const (
+	// pollingIntervalElapsedDivisor makes the polling interval grow to a
+	// fraction of the time already spent waiting for a proof, so long proofs
+	// are polled less often.
+	pollingIntervalElapsedDivisor = 10
+	// maxPollingIntervalFactor caps the polling interval to a multiple of the
+	// configured one.
+	maxPollingIntervalFactor = 10
+	// pollingIntervalJitter is the maximum fraction of the polling interval
+	// randomly added or subtracted to avoid provers being polled in lockstep.
+	pollingIntervalJitter = 0.1
 )
 
 // Prover abstraction of the grpc prover client.
@@ -29,15 +44,19 @@ type Prover struct {
 	id                        string
 	address                   net.Addr
 	proofStatePollingInterval types.Duration
+	maxLoggedProofLength      int
 	stream                    pb.AggregatorService_ChannelServer
 }
 
 // New returns a new Prover instance.
-func New(stream pb.AggregatorService_ChannelServer, addr net.Addr, proofStatePollingInterval types.Duration) (*Prover, error) {
+// Proofs included in the returned errors are truncated to
+// maxLoggedProofLength characters, 0 meaning no truncation.
+func New(stream pb.AggregatorService_ChannelServer, addr net.Addr, proofStatePollingInterval types.Duration, maxLoggedProofLength int) (*Prover, error) {
 	p := &Prover{
 		stream:                    stream,
 		address:                   addr,
 		proofStatePollingInterval: proofStatePollingInterval,
+		maxLoggedProofLength:      maxLoggedProofLength,
 	}
 	status, err := p.Status()
 	if err != nil {
@@ -156,18 +175,18 @@ func (p *Prover) AggregatedProof(inputProof1, inputProof2 string) (*string, erro
 		switch msg.GenAggregatedProofResponse.Result {
 		case pb.Result_RESULT_UNSPECIFIED:
 			return nil, fmt.Errorf("failed to aggregate proofs %s, %w, input 1 %s, input 2 %s",
-				msg.GenAggregatedProofResponse.String(), ErrUnspecified, inputProof1, inputProof2)
+				msg.GenAggregatedProofResponse.String(), ErrUnspecified, p.truncate(inputProof1), p.truncate(inputProof2))
 		case pb.Result_RESULT_OK:
 			return &msg.GenAggregatedProofResponse.Id, nil
 		case pb.Result_RESULT_ERROR:
 			return nil, fmt.Errorf("failed to aggregate proofs %s, %w, input 1 %s, input 2 %s",
-				msg.GenAggregatedProofResponse.String(), ErrBadRequest, inputProof1, inputProof2)
+				msg.GenAggregatedProofResponse.String(), ErrBadRequest, p.truncate(inputProof1), p.truncate(inputProof2))
 		case pb.Result_RESULT_INTERNAL_ERROR:
 			return nil, fmt.Errorf("failed to aggregate proofs %s, %w, input 1 %s, input 2 %s",
-				msg.GenAggregatedProofResponse.String(), ErrProverInternalError, inputProof1, inputProof2)
+				msg.GenAggregatedProofResponse.String(), ErrProverInternalError, p.truncate(inputProof1), p.truncate(inputProof2))
 		default:
 			return nil, fmt.Errorf("failed to aggregate proofs %s, %w, input 1 %s, input 2 %s",
-				msg.GenAggregatedProofResponse.String(), ErrUnknown, inputProof1, inputProof2)
+				msg.GenAggregatedProofResponse.String(), ErrUnknown, p.truncate(inputProof1), p.truncate(inputProof2))
 		}
 	}
 
@@ -196,18 +215,18 @@ func (p *Prover) FinalProof(inputProof string, aggregatorAddr string) (*string,
 		switch msg.GenFinalProofResponse.Result {
 		case pb.Result_RESULT_UNSPECIFIED:
 			return nil, fmt.Errorf("failed to generate final proof %s, %w, input %s",
-				msg.GenFinalProofResponse.String(), ErrUnspecified, inputProof)
+				msg.GenFinalProofResponse.String(), ErrUnspecified, p.truncate(inputProof))
 		case pb.Result_RESULT_OK:
 			return &msg.GenFinalProofResponse.Id, nil
 		case pb.Result_RESULT_ERROR:
 			return nil, fmt.Errorf("failed to generate final proof %s, %w, input %s",
-				msg.GenFinalProofResponse.String(), ErrBadRequest, inputProof)
+				msg.GenFinalProofResponse.String(), ErrBadRequest, p.truncate(inputProof))
 		case pb.Result_RESULT_INTERNAL_ERROR:
 			return nil, fmt.Errorf("failed to generate final proof %s, %w, input %s",
-				msg.GenFinalProofResponse.String(), ErrProverInternalError, inputProof)
+				msg.GenFinalProofResponse.String(), ErrProverInternalError, p.truncate(inputProof))
 		default:
 			return nil, fmt.Errorf("failed to generate final proof %s, %w, input %s",
-				msg.GenFinalProofResponse.String(), ErrUnknown, inputProof)
+				msg.GenFinalProofResponse.String(), ErrUnknown, p.truncate(inputProof))
 		}
 	}
 	return nil, fmt.Errorf("%w, wanted %T, got %T", ErrBadProverResponse, &pb.ProverMessage_GenFinalProofResponse{}, res.Response)
@@ -282,6 +301,7 @@ func (p *Prover) waitProof(ctx context.Context, proofID string) (*pb.GetProofRes
 		},
 	}
 
+	waitingSince := time.Now()
 	for {
 		select {
 		case <-ctx.Done():
@@ -294,28 +314,33 @@ func (p *Prover) waitProof(ctx context.Context, proofID string) (*pb.GetProofRes
 			if msg, ok := res.Response.(*pb.ProverMessage_GetProofResponse); ok {
 				switch msg.GetProofResponse.Result {
 				case pb.GetProofResponse_RESULT_PENDING:
-					time.Sleep(p.proofStatePollingInterval.Duration)
+					time.Sleep(pollingInterval(p.proofStatePollingInterval.Duration, time.Since(waitingSince)))
 					continue
 				case pb.GetProofResponse_RESULT_UNSPECIFIED:
 					return nil, fmt.Errorf("failed to get proof ID: %s, %w, prover response: %s",
-						proofID, ErrUnspecified, msg.GetProofResponse.String())
+						proofID, ErrUnspecified, p.truncate(msg.GetProofResponse.String()))
 				case pb.GetProofResponse_RESULT_COMPLETED_OK:
+					// provers that don't echo the ID leave it empty
+					if msg.GetProofResponse.Id != "" && msg.GetProofResponse.Id != proofID {
+						return nil, fmt.Errorf("failed to get proof ID: %s, %w, prover response: %s",
+							proofID, ErrProofIDMismatch, p.truncate(msg.GetProofResponse.String()))
+					}
 					return msg.GetProofResponse, nil
 				case pb.GetProofResponse_RESULT_ERROR:
 					return nil, fmt.Errorf("failed to get proof with ID %s, %w, prover response: %s",
-						proofID, ErrBadRequest, msg.GetProofResponse.String())
+						proofID, ErrBadRequest, p.truncate(msg.GetProofResponse.String()))
 				case pb.GetProofResponse_RESULT_COMPLETED_ERROR:
 					return nil, fmt.Errorf("failed to get proof with ID %s, %w, prover response: %s",
-						proofID, ErrProverCompletedError, msg.GetProofResponse.String())
+						proofID, ErrProverCompletedError, p.truncate(msg.GetProofResponse.String()))
 				case pb.GetProofResponse_RESULT_INTERNAL_ERROR:
 					return nil, fmt.Errorf("failed to get proof ID: %s, %w, prover response: %s",
-						proofID, ErrProverInternalError, msg.GetProofResponse.String())
+						proofID, ErrProverInternalError, p.truncate(msg.GetProofResponse.String()))
 				case pb.GetProofResponse_RESULT_CANCEL:
 					return nil, fmt.Errorf("proof generation was cancelled for proof ID %s, %w, prover response: %s",
-						proofID, ErrProofCanceled, msg.GetProofResponse.String())
+						proofID, ErrProofCanceled, p.truncate(msg.GetProofResponse.String()))
 				default:
 					return nil, fmt.Errorf("failed to get proof ID: %s, %w, prover response: %s",
-						proofID, ErrUnknown, msg.GetProofResponse.String())
+						proofID, ErrUnknown, p.truncate(msg.GetProofResponse.String()))
 				}
 			}
 			return nil, fmt.Errorf("%w, wanted %T, got %T", ErrBadProverResponse, &pb.ProverMessage_GetProofResponse{}, res.Response)
@@ -323,6 +348,31 @@ func (p *Prover) waitProof(ctx context.Context, proofID string) (*pb.GetProofRes
 	}
 }
 
+// truncate shortens s to the prover maxLoggedProofLength so that proofs do
+// not flood the logs, noting the original length.
+func (p *Prover) truncate(s string) string {
+	if p.maxLoggedProofLength <= 0 || len(s) <= p.maxLoggedProofLength {
+		return s
+	}
+	return fmt.Sprintf("%s...(truncated, %d chars)", s[:p.maxLoggedProofLength], len(s))
+}
+
+// pollingInterval returns the time to wait before polling again for a proof
+// that has been pending for elapsed. It starts at base, grows with the time
+// already waited up to maxPollingIntervalFactor times base, and is jittered by
+// pollingIntervalJitter.
+func pollingInterval(base, elapsed time.Duration) time.Duration {
+	interval := elapsed / pollingIntervalElapsedDivisor
+	if interval < base {
+		interval = base
+	}
+	if maxInterval := base * maxPollingIntervalFactor; interval > maxInterval {
+		interval = maxInterval
+	}
+	jitter := (rand.Float64()*2 - 1) * pollingIntervalJitter * float64(interval) //nolint:gosec
+	return interval + time.Duration(jitter)
+}
+
 // call sends a message to the prover and waits to receive the response over
 // the connection stream.
 func (p *Prover) call(req *pb.AggregatorMessage) (*pb.ProverMessage, error) {
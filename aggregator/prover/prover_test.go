@@ -0,0 +1,146 @@
+package prover
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/0xPolygonHermez/zkevm-node/aggregator/pb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPollingInterval(t *testing.T) {
+	const base = time.Second
+
+	testCases := []struct {
+		name    string
+		elapsed time.Duration
+		min     time.Duration
+		max     time.Duration
+	}{
+		{
+			name:    "short proof polls near the base rate",
+			elapsed: 2 * time.Second,
+			min:     900 * time.Millisecond,
+			max:     1100 * time.Millisecond,
+		},
+		{
+			name:    "long proof backs off",
+			elapsed: 50 * time.Second,
+			min:     4500 * time.Millisecond,
+			max:     5500 * time.Millisecond,
+		},
+		{
+			name:    "very long proof is capped",
+			elapsed: time.Hour,
+			min:     9 * time.Second,
+			max:     11 * time.Second,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			for i := 0; i < 100; i++ {
+				interval := pollingInterval(base, tc.elapsed)
+				assert.GreaterOrEqual(t, interval, tc.min)
+				assert.LessOrEqual(t, interval, tc.max)
+			}
+		})
+	}
+}
+
+// streamMock is a prover stream answering every request with the configured
+// get proof response.
+type streamMock struct {
+	pb.AggregatorService_ChannelServer
+	res *pb.GetProofResponse
+}
+
+func (s *streamMock) Send(*pb.AggregatorMessage) error { return nil }
+
+func (s *streamMock) Recv() (*pb.ProverMessage, error) {
+	return &pb.ProverMessage{
+		Response: &pb.ProverMessage_GetProofResponse{GetProofResponse: s.res},
+	}, nil
+}
+
+func TestWaitRecursiveProofID(t *testing.T) {
+	testCases := []struct {
+		name        string
+		responseID  string
+		expectedErr error
+	}{
+		{
+			name:       "proof for the requested ID",
+			responseID: "proofId",
+		},
+		{
+			name:       "proof without ID accepted",
+			responseID: "",
+		},
+		{
+			name:        "proof for another ID rejected",
+			responseID:  "otherProofId",
+			expectedErr: ErrProofIDMismatch,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			p := &Prover{
+				stream: &streamMock{res: &pb.GetProofResponse{
+					Id:     tc.responseID,
+					Result: pb.GetProofResponse_RESULT_COMPLETED_OK,
+					Proof:  &pb.GetProofResponse_RecursiveProof{RecursiveProof: "recursiveProof"},
+				}},
+			}
+
+			proof, err := p.WaitRecursiveProof(context.Background(), "proofId")
+
+			if tc.expectedErr != nil {
+				assert.ErrorIs(t, err, tc.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, "recursiveProof", proof)
+		})
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	testCases := []struct {
+		name      string
+		maxLength int
+		s         string
+		expected  string
+	}{
+		{
+			name:      "no truncation",
+			maxLength: 0,
+			s:         "0123456789",
+			expected:  "0123456789",
+		},
+		{
+			name:      "shorter than max",
+			maxLength: 10,
+			s:         "0123456789",
+			expected:  "0123456789",
+		},
+		{
+			name:      "longer than max",
+			maxLength: 4,
+			s:         "0123456789",
+			expected:  "0123...(truncated, 10 chars)",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			p := &Prover{maxLoggedProofLength: tc.maxLength}
+
+			assert.Equal(t, tc.expected, p.truncate(tc.s))
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package aggregator
+
+import (
+	"sync"
+	"time"
+)
+
+// proverBlocklist tracks the consecutive proof generation failures of each
+// prover, keyed by prover ID, and keeps the provers that reach the maximum
+// allowed out of the work dispatch for a while.
+type proverBlocklist struct {
+	maxConsecutiveFailures int
+	duration               time.Duration
+
+	mu           sync.Mutex
+	failures     map[string]int
+	blockedUntil map[string]time.Time
+}
+
+func newProverBlocklist(maxConsecutiveFailures int, duration time.Duration) *proverBlocklist {
+	return &proverBlocklist{
+		maxConsecutiveFailures: maxConsecutiveFailures,
+		duration:               duration,
+		failures:               make(map[string]int),
+		blockedUntil:           make(map[string]time.Time),
+	}
+}
+
+// recordFailure counts a failure for the prover. It returns true if the
+// prover has been blocklisted because of it.
+func (b *proverBlocklist) recordFailure(now func() time.Time, proverID string) bool {
+	if b.maxConsecutiveFailures <= 0 {
+		return false
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures[proverID]++
+	if b.failures[proverID] < b.maxConsecutiveFailures {
+		return false
+	}
+	delete(b.failures, proverID)
+	b.blockedUntil[proverID] = now().Add(b.duration)
+	return true
+}
+
+// recordSuccess resets the consecutive failures of the prover.
+func (b *proverBlocklist) recordSuccess(proverID string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	delete(b.failures, proverID)
+}
+
+// isBlocked returns true if the prover must not receive new work.
+func (b *proverBlocklist) isBlocked(now func() time.Time, proverID string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	until, ok := b.blockedUntil[proverID]
+	if !ok {
+		return false
+	}
+	if now().Before(until) {
+		return true
+	}
+	delete(b.blockedUntil, proverID)
+	return false
+}
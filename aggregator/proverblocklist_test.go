@@ -0,0 +1,39 @@
+package aggregator
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProverBlocklist(t *testing.T) {
+	assert := assert.New(t)
+	clk := newFakeClock(time.Now())
+	b := newProverBlocklist(3, time.Minute)
+
+	assert.False(b.recordFailure(clk.Now, "prover1"))
+	assert.False(b.recordFailure(clk.Now, "prover1"))
+	b.recordSuccess("prover1")
+	assert.False(b.recordFailure(clk.Now, "prover1"))
+	assert.False(b.recordFailure(clk.Now, "prover1"))
+	assert.False(b.isBlocked(clk.Now, "prover1"))
+
+	assert.True(b.recordFailure(clk.Now, "prover1"))
+	assert.True(b.isBlocked(clk.Now, "prover1"))
+	assert.False(b.isBlocked(clk.Now, "prover2"))
+
+	clk.Advance(time.Minute - time.Nanosecond)
+	assert.True(b.isBlocked(clk.Now, "prover1"))
+	clk.Advance(time.Nanosecond)
+	assert.False(b.isBlocked(clk.Now, "prover1"))
+}
+
+func TestProverBlocklistDisabled(t *testing.T) {
+	b := newProverBlocklist(0, time.Minute)
+
+	for i := 0; i < 10; i++ {
+		assert.False(t, b.recordFailure(time.Now, "prover1"))
+	}
+	assert.False(t, b.isBlocked(time.Now, "prover1"))
+}
@@ -0,0 +1,52 @@
+package aggregator
+
+import "sync"
+
+// proverLocks counts, per prover ID, the proof generations currently locked
+// in the state by each prover, capping them to a maximum.
+type proverLocks struct {
+	max int
+
+	mu     sync.Mutex
+	locked map[string]int
+}
+
+func newProverLocks(max int) *proverLocks {
+	return &proverLocks{
+		max:    max,
+		locked: make(map[string]int),
+	}
+}
+
+// full returns true if the prover can't lock another proof generation. A
+// zero maximum means no limit.
+func (l *proverLocks) full(proverID string) bool {
+	if l.max <= 0 {
+		return false
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.locked[proverID] >= l.max
+}
+
+// add counts a new proof generation locked by the prover.
+func (l *proverLocks) add(proverID string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.locked[proverID]++
+}
+
+// release discounts a proof generation of the prover once it is completed or
+// unlocked.
+func (l *proverLocks) release(proverID string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.locked[proverID]--
+	if l.locked[proverID] <= 0 {
+		delete(l.locked, proverID)
+	}
+}
@@ -0,0 +1,31 @@
+package aggregator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProverLocks(t *testing.T) {
+	assert := assert.New(t)
+	l := newProverLocks(2)
+
+	assert.False(l.full("prover1"))
+	l.add("prover1")
+	assert.False(l.full("prover1"))
+	l.add("prover1")
+	assert.True(l.full("prover1"))
+	assert.False(l.full("prover2"))
+
+	l.release("prover1")
+	assert.False(l.full("prover1"))
+}
+
+func TestProverLocksDisabled(t *testing.T) {
+	l := newProverLocks(0)
+
+	for i := 0; i < 5; i++ {
+		l.add("prover1")
+	}
+	assert.False(t, l.full("prover1"))
+}
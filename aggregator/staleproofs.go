@@ -0,0 +1,56 @@
+package aggregator
+
+import (
+	"sync"
+	"time"
+)
+
+// staleProofs tracks since when proofs have been found starting below the
+// next batch to verify, so they are only deleted once they have been stale
+// for the deletion grace window.
+type staleProofs struct {
+	mu    sync.Mutex
+	since map[[2]uint64]time.Time
+}
+
+func newStaleProofs() *staleProofs {
+	return &staleProofs{
+		since: make(map[[2]uint64]time.Time),
+	}
+}
+
+// elapsed returns how long the proof has been stale, starting to count at now
+// the first time it is found stale.
+func (s *staleProofs) elapsed(batchNumber, batchNumberFinal uint64, now time.Time) time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := [2]uint64{batchNumber, batchNumberFinal}
+	since, ok := s.since[key]
+	if !ok {
+		s.since[key] = now
+		return 0
+	}
+	return now.Sub(since)
+}
+
+// remove forgets a stale proof once it is deleted.
+func (s *staleProofs) remove(batchNumber, batchNumberFinal uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.since, [2]uint64{batchNumber, batchNumberFinal})
+}
+
+// prune forgets the stale proofs ending up to batchNumberFinal, once they have
+// been cleaned up from the state.
+func (s *staleProofs) prune(batchNumberFinal uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key := range s.since {
+		if key[1] <= batchNumberFinal {
+			delete(s.since, key)
+		}
+	}
+}
@@ -0,0 +1,25 @@
+package aggregator
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStaleProofs(t *testing.T) {
+	assert := assert.New(t)
+	s := newStaleProofs()
+	now := time.Now()
+
+	assert.Zero(s.elapsed(1, 3, now))
+	assert.Equal(time.Minute, s.elapsed(1, 3, now.Add(time.Minute)))
+	assert.Zero(s.elapsed(4, 5, now.Add(time.Minute)))
+
+	s.remove(1, 3)
+	assert.Zero(s.elapsed(1, 3, now.Add(2*time.Minute)))
+
+	s.prune(3)
+	assert.Zero(s.elapsed(1, 3, now.Add(3*time.Minute)))
+	assert.Equal(2*time.Minute, s.elapsed(4, 5, now.Add(3*time.Minute)))
+}
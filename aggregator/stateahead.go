@@ -0,0 +1,34 @@
+package aggregator
+
+import (
+	"sync"
+	"time"
+)
+
+// stateAheadOfL1 tracks since when the last verified batch of the state has
+// been ahead of the last one verified on L1, waiting for the synchronizer to
+// handle the reorg of the L1 verifications.
+type stateAheadOfL1 struct {
+	mu    sync.Mutex
+	since time.Time
+}
+
+// elapsed returns how long the state has been ahead of L1, starting to count
+// at now if it was not ahead before.
+func (s *stateAheadOfL1) elapsed(now time.Time) time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.since.IsZero() {
+		s.since = now
+	}
+	return now.Sub(s.since)
+}
+
+// reset stops the count once L1 caught up with the state.
+func (s *stateAheadOfL1) reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.since = time.Time{}
+}
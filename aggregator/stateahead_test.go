@@ -0,0 +1,21 @@
+package aggregator
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStateAheadOfL1(t *testing.T) {
+	assert := assert.New(t)
+	var s stateAheadOfL1
+	now := time.Now()
+
+	assert.Zero(s.elapsed(now))
+	assert.Equal(time.Minute, s.elapsed(now.Add(time.Minute)))
+
+	s.reset()
+	assert.Zero(s.elapsed(now.Add(2 * time.Minute)))
+	assert.Equal(time.Minute, s.elapsed(now.Add(3*time.Minute)))
+}
@@ -0,0 +1,35 @@
+package aggregator
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/0xPolygonHermez/zkevm-node/state"
+)
+
+// listProofsPageSize is the number of stored proofs read from the state at
+// once when walking all of them.
+const listProofsPageSize = 100
+
+// forEachStoredProof calls fn for every stored proof, ordered by batch number
+// and final batch number. The proofs are read in pages so they are never all
+// loaded in memory. It stops at the first error returned by fn.
+func (a *Aggregator) forEachStoredProof(ctx context.Context, fn func(*state.Proof) error) error {
+	var fromBatch, fromBatchFinal uint64
+	for {
+		proofs, err := a.State.ListProofs(ctx, fromBatch, fromBatchFinal, listProofsPageSize, nil)
+		if err != nil {
+			return fmt.Errorf("failed to list proofs, %w", err)
+		}
+		for _, proof := range proofs {
+			if err := fn(proof); err != nil {
+				return err
+			}
+		}
+		if len(proofs) < listProofsPageSize {
+			return nil
+		}
+		last := proofs[len(proofs)-1]
+		fromBatch, fromBatchFinal = last.BatchNumber, last.BatchNumberFinal+1
+	}
+}
@@ -0,0 +1,58 @@
+package aggregator
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/0xPolygonHermez/zkevm-node/aggregator/mocks"
+	"github.com/0xPolygonHermez/zkevm-node/state"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestForEachStoredProof(t *testing.T) {
+	assert := assert.New(t)
+	stateMock := mocks.NewStateMock(t)
+	a, err := New(newTestConfig(), stateMock, mocks.NewEthTxManager(t), mocks.NewEtherman(t))
+	require.NoError(t, err)
+	newPage := func(from, n uint64) []*state.Proof {
+		proofs := make([]*state.Proof, 0, n)
+		for batchNum := from; batchNum < from+n; batchNum++ {
+			proofs = append(proofs, &state.Proof{BatchNumber: batchNum, BatchNumberFinal: batchNum})
+		}
+		return proofs
+	}
+	stateMock.On("ListProofs", mock.Anything, uint64(0), uint64(0), uint64(listProofsPageSize), nil).Return(newPage(1, listProofsPageSize), nil).Once()
+	stateMock.On("ListProofs", mock.Anything, uint64(listProofsPageSize), uint64(listProofsPageSize+1), uint64(listProofsPageSize), nil).Return(newPage(listProofsPageSize+1, 10), nil).Once()
+
+	var walked []uint64
+	err = a.forEachStoredProof(context.Background(), func(proof *state.Proof) error {
+		walked = append(walked, proof.BatchNumber)
+		return nil
+	})
+
+	assert.NoError(err)
+	assert.Len(walked, listProofsPageSize+10)
+	for i, batchNum := range walked {
+		assert.Equal(uint64(i+1), batchNum)
+	}
+}
+
+func TestForEachStoredProofError(t *testing.T) {
+	errBanana := errors.New("banana")
+	stateMock := mocks.NewStateMock(t)
+	a, err := New(newTestConfig(), stateMock, mocks.NewEthTxManager(t), mocks.NewEtherman(t))
+	require.NoError(t, err)
+	stateMock.On("ListProofs", mock.Anything, uint64(0), uint64(0), uint64(listProofsPageSize), nil).Return([]*state.Proof{{BatchNumber: 1, BatchNumberFinal: 1}, {BatchNumber: 2, BatchNumberFinal: 2}}, nil).Once()
+
+	var calls int
+	err = a.forEachStoredProof(context.Background(), func(proof *state.Proof) error {
+		calls++
+		return errBanana
+	})
+
+	assert.ErrorIs(t, err, errBanana)
+	assert.Equal(t, 1, calls)
+}
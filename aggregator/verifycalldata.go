@@ -0,0 +1,37 @@
+package aggregator
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// verifyCalldata is the batch verification tx exported for manual submission.
+type verifyCalldata struct {
+	To   *common.Address `json:"to"`
+	Data hexutil.Bytes   `json:"data"`
+}
+
+// verifyCalldataFileName returns the name of the file the batch verification
+// tx of the given batch range is exported to.
+func verifyCalldataFileName(batchNumber, batchNumberFinal uint64) string {
+	return fmt.Sprintf("verify-batches-%d-%d.json", batchNumber, batchNumberFinal)
+}
+
+// exportVerifyCalldata writes the batch verification tx destination and data
+// to a JSON file in dir named by the batch range.
+func exportVerifyCalldata(dir string, batchNumber, batchNumberFinal uint64, to *common.Address, data []byte) (string, error) {
+	b, err := json.Marshal(verifyCalldata{To: to, Data: data})
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, verifyCalldataFileName(batchNumber, batchNumberFinal))
+	if err := os.WriteFile(path, b, 0600); err != nil { //nolint:gomnd
+		return "", err
+	}
+	return path, nil
+}
@@ -0,0 +1,37 @@
+package aggregator
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportVerifyCalldata(t *testing.T) {
+	dir := t.TempDir()
+	to := common.HexToAddress("0x1")
+	data := []byte("data")
+
+	path, err := exportVerifyCalldata(dir, 23, 42, &to, data)
+
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "verify-batches-23-42.json"), path)
+	b, err := os.ReadFile(path)
+	require.NoError(t, err)
+	var exported verifyCalldata
+	require.NoError(t, json.Unmarshal(b, &exported))
+	assert.Equal(t, &to, exported.To)
+	assert.Equal(t, data, []byte(exported.Data))
+}
+
+func TestExportVerifyCalldataMissingDir(t *testing.T) {
+	to := common.HexToAddress("0x1")
+
+	_, err := exportVerifyCalldata(filepath.Join(t.TempDir(), "missing"), 23, 42, &to, []byte("data"))
+
+	assert.Error(t, err)
+}
@@ -431,6 +431,14 @@ func Test_Defaults(t *testing.T) {
 			path:          "Aggregator.GeneratingProofCleanupThreshold",
 			expectedValue: "10m",
 		},
+		{
+			path:          "Aggregator.CleanupUngeneratedOnStart",
+			expectedValue: true,
+		},
+		{
+			path:          "Aggregator.MaxStateAheadOfL1",
+			expectedValue: types.NewDuration(0),
+		},
 	}
 	file, err := os.CreateTemp("", "genesisConfig")
 	require.NoError(t, err)
@@ -125,6 +125,8 @@ TxProfitabilityMinReward = "1.1"
 ProofStatePollingInterval = "5s"
 CleanupLockedProofsInterval = "2m"
 GeneratingProofCleanupThreshold = "10m"
+CleanupUngeneratedOnStart = true
+MaxStateAheadOfL1 = "0s"
 
 [L2GasPriceSuggester]
 Type = "follower"
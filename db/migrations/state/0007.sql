@@ -0,0 +1,7 @@
+-- +migrate Up
+ALTER TABLE state.proof
+    ADD COLUMN attempt_id VARCHAR;
+
+-- +migrate Down
+ALTER TABLE state.proof
+    DROP COLUMN attempt_id;
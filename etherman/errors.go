@@ -1,8 +1,11 @@
 package etherman
 
 import (
+	"encoding/hex"
 	"errors"
 	"strings"
+
+	"github.com/ethereum/go-ethereum/rpc"
 )
 
 var (
@@ -22,6 +25,8 @@ var (
 	ErrNoSigner = errors.New("no signer to authorize the transaction with")
 	// ErrMissingTrieNode means that a node is missing on the trie
 	ErrMissingTrieNode = errors.New("missing trie node")
+	// ErrInvalidProof the verifier contract rejected the proof
+	ErrInvalidProof = errors.New("invalid proof")
 
 	errorsCache = map[string]error{
 		ErrGasRequiredExceedsAllowance.Error():             ErrGasRequiredExceedsAllowance,
@@ -35,6 +40,21 @@ var (
 	}
 )
 
+// isInvalidProofError returns true if err is the revert of a contract call
+// with the InvalidProof custom error.
+func isInvalidProofError(err error) bool {
+	var dataErr rpc.DataError
+	if !errors.As(err, &dataErr) {
+		return false
+	}
+	data, ok := dataErr.ErrorData().(string)
+	if !ok {
+		return false
+	}
+	selector := hex.EncodeToString(invalidProofErrorSignatureHash[:4])
+	return strings.HasPrefix(strings.TrimPrefix(data, "0x"), selector)
+}
+
 func tryParseError(err error) (error, bool) {
 	parsedError, exists := errorsCache[err.Error()]
 	if !exists {
@@ -1,6 +1,7 @@
 package etherman
 
 import (
+	"encoding/hex"
 	"fmt"
 	"testing"
 
@@ -35,3 +36,18 @@ func TestTryParseWithNonExistingErr(t *testing.T) {
 	assert.Nil(t, actualErr)
 	assert.False(t, ok)
 }
+
+type dataErrorMock struct {
+	data interface{}
+}
+
+func (e dataErrorMock) Error() string          { return "execution reverted" }
+func (e dataErrorMock) ErrorData() interface{} { return e.data }
+
+func TestIsInvalidProofError(t *testing.T) {
+	assert.True(t, isInvalidProofError(dataErrorMock{data: "0x" + hex.EncodeToString(invalidProofErrorSignatureHash[:4])}))
+	assert.True(t, isInvalidProofError(fmt.Errorf("wrapped: %w", dataErrorMock{data: "0x" + hex.EncodeToString(invalidProofErrorSignatureHash[:4])})))
+	assert.False(t, isInvalidProofError(dataErrorMock{data: "0x12345678"}))
+	assert.False(t, isInvalidProofError(dataErrorMock{}))
+	assert.False(t, isInvalidProofError(fmt.Errorf("some non-existing err")))
+}
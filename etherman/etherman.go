@@ -61,6 +61,9 @@ var (
 	proveNonDeterministicPendingStateSignatureHash = crypto.Keccak256Hash([]byte("ProveNonDeterministicPendingState(bytes32,bytes32)"))
 	overridePendingStateSignatureHash              = crypto.Keccak256Hash([]byte("OverridePendingState(uint64,bytes32,address)"))
 
+	// Custom errors
+	invalidProofErrorSignatureHash = crypto.Keccak256Hash([]byte("InvalidProof()"))
+
 	// Proxy events
 	initializedSignatureHash    = crypto.Keccak256Hash([]byte("Initialized(uint8)"))
 	adminChangedSignatureHash   = crypto.Keccak256Hash([]byte("AdminChanged(address,address)"))
@@ -76,6 +79,8 @@ var (
 	ErrPrivateKeyNotFound = errors.New("can't find sender private key to sign tx")
 )
 
+const pendStateNum = 0 // TODO hardcoded for now until we implement the pending state feature
+
 // SequencedBatchesSigHash returns the hash for the `SequenceBatches` event.
 func SequencedBatchesSigHash() common.Hash { return sequencedBatchesEventSignatureHash }
 
@@ -539,19 +544,11 @@ func (etherMan *Client) BuildTrustedVerifyBatchesTxData(lastVerifiedBatch, newVe
 	opts.GasLimit = uint64(1)
 	opts.GasPrice = big.NewInt(1)
 
-	var newLocalExitRoot [32]byte
-	copy(newLocalExitRoot[:], inputs.NewLocalExitRoot)
-
-	var newStateRoot [32]byte
-	copy(newStateRoot[:], inputs.NewStateRoot)
-
-	proof, err := encoding.DecodeBytes(&inputs.FinalProof.Proof)
+	newLocalExitRoot, newStateRoot, proof, err := decodeFinalProofInputs(inputs)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to decode proof, err: %w", err)
+		return nil, nil, err
 	}
 
-	const pendStateNum = 0 // TODO hardcoded for now until we implement the pending state feature
-
 	tx, err := etherMan.ZkEVM.VerifyBatchesTrustedAggregator(
 		&opts,
 		pendStateNum,
@@ -571,6 +568,45 @@ func (etherMan *Client) BuildTrustedVerifyBatchesTxData(lastVerifiedBatch, newVe
 	return tx.To(), tx.Data(), nil
 }
 
+// CheckTrustedVerifyBatches simulates with an eth_call, from the provided
+// sender, the PoE SC method TrustedVerifyBatches with the final proof, so the
+// proof is checked by the verifier contract before sending the tx. It returns
+// ErrInvalidProof if the verifier rejects the proof.
+func (etherMan *Client) CheckTrustedVerifyBatches(ctx context.Context, sender common.Address, lastVerifiedBatch, newVerifiedBatch uint64, inputs *ethmanTypes.FinalProofInputs) error {
+	newLocalExitRoot, newStateRoot, proof, err := decodeFinalProofInputs(inputs)
+	if err != nil {
+		return err
+	}
+
+	caller := polygonzkevm.PolygonzkevmCallerRaw{Contract: &etherMan.ZkEVM.PolygonzkevmCaller}
+	var out []interface{}
+	err = caller.Call(&bind.CallOpts{Context: ctx, From: sender}, &out, "verifyBatchesTrustedAggregator",
+		uint64(pendStateNum), lastVerifiedBatch, newVerifiedBatch, newLocalExitRoot, newStateRoot, proof)
+	if err != nil {
+		if isInvalidProofError(err) {
+			return ErrInvalidProof
+		}
+		if parsedErr, ok := tryParseError(err); ok {
+			err = parsedErr
+		}
+		return err
+	}
+	return nil
+}
+
+// decodeFinalProofInputs converts the final proof inputs to the types expected
+// by the PoE SC method TrustedVerifyBatches.
+func decodeFinalProofInputs(inputs *ethmanTypes.FinalProofInputs) (newLocalExitRoot, newStateRoot [32]byte, proof []byte, err error) {
+	copy(newLocalExitRoot[:], inputs.NewLocalExitRoot)
+	copy(newStateRoot[:], inputs.NewStateRoot)
+
+	proof, err = encoding.DecodeBytes(&inputs.FinalProof.Proof)
+	if err != nil {
+		return newLocalExitRoot, newStateRoot, nil, fmt.Errorf("failed to decode proof, err: %w", err)
+	}
+	return newLocalExitRoot, newStateRoot, proof, nil
+}
+
 // GetSendSequenceFee get super/trusted sequencer fee
 func (etherMan *Client) GetSendSequenceFee(numBatches uint64) (*big.Int, error) {
 	f, err := etherMan.ZkEVM.BatchFee(&bind.CallOpts{Pending: false})
@@ -928,6 +964,22 @@ func (etherMan *Client) GetLatestBlockNumber(ctx context.Context) (uint64, error
 	return header.Number.Uint64(), nil
 }
 
+// GetBalance gets the latest balance of the account from the ethereum
+func (etherMan *Client) GetBalance(ctx context.Context, account common.Address) (*big.Int, error) {
+	return etherMan.EthClient.BalanceAt(ctx, account, nil)
+}
+
+// GetSequencedBatchAccInputHash gets the accumulated input hash stored in the
+// PoE SC for the batch. It is only stored for the last batch of each
+// sequence, an empty hash is returned for any other batch.
+func (etherMan *Client) GetSequencedBatchAccInputHash(ctx context.Context, batchNumber uint64) (common.Hash, error) {
+	sequencedBatch, err := etherMan.ZkEVM.SequencedBatches(&bind.CallOpts{Context: ctx}, batchNumber)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return sequencedBatch.AccInputHash, nil
+}
+
 // GetLatestBlockTimestamp gets the latest block timestamp from the ethereum
 func (etherMan *Client) GetLatestBlockTimestamp(ctx context.Context) (uint64, error) {
 	header, err := etherMan.EthClient.HeaderByNumber(ctx, nil)
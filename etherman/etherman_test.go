@@ -9,6 +9,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/0xPolygonHermez/zkevm-node/aggregator/pb"
 	"github.com/0xPolygonHermez/zkevm-node/etherman/smartcontracts/polygonzkevm"
 	"github.com/0xPolygonHermez/zkevm-node/etherman/smartcontracts/polygonzkevmbridge"
 	ethmanTypes "github.com/0xPolygonHermez/zkevm-node/etherman/types"
@@ -237,6 +238,85 @@ func TestVerifyBatchEvent(t *testing.T) {
 	assert.Equal(t, 0, order[blocks[2].BlockHash][1].Pos)
 }
 
+func TestGetBalance(t *testing.T) {
+	// Set up testing environment
+	etherman, _, auth, _, _ := newTestingEnv()
+
+	balance, err := etherman.GetBalance(context.Background(), auth.From)
+	require.NoError(t, err)
+	assert.Positive(t, balance.Sign())
+
+	balance, err = etherman.GetBalance(context.Background(), common.HexToAddress("0x1234"))
+	require.NoError(t, err)
+	assert.Zero(t, balance.Sign())
+}
+
+func TestGetSequencedBatchAccInputHash(t *testing.T) {
+	// Set up testing environment
+	etherman, ethBackend, auth, _, _ := newTestingEnv()
+
+	ctx := context.Background()
+	initBlock, err := etherman.EthClient.BlockByNumber(ctx, nil)
+	require.NoError(t, err)
+
+	rawTxs := "f84901843b9aca00827b0c945fbdb2315678afecb367f032d93f642f64180aa380a46057361d00000000000000000000000000000000000000000000000000000000000000048203e9808073efe1fa2d3e27f26f32208550ea9b0274d49050b816cadab05a771f4275d0242fd5d92b3fb89575c070e6c930587c520ee65a3aa8cfe382fcad20421bf51d621c"
+	tx := polygonzkevm.PolygonZkEVMBatchData{
+		GlobalExitRoot:     common.Hash{},
+		Timestamp:          initBlock.Time(),
+		MinForcedTimestamp: 0,
+		Transactions:       common.Hex2Bytes(rawTxs),
+	}
+	_, err = etherman.ZkEVM.SequenceBatches(auth, []polygonzkevm.PolygonZkEVMBatchData{tx, tx}, auth.From)
+	require.NoError(t, err)
+
+	// Mine the tx in a block
+	ethBackend.Commit()
+
+	// only the last batch of the sequence stores its accumulated input hash
+	accInputHash, err := etherman.GetSequencedBatchAccInputHash(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, common.Hash{}, accInputHash)
+	accInputHash, err = etherman.GetSequencedBatchAccInputHash(ctx, 2)
+	require.NoError(t, err)
+	assert.NotEqual(t, common.Hash{}, accInputHash)
+}
+
+func TestCheckTrustedVerifyBatches(t *testing.T) {
+	// Set up testing environment
+	etherman, ethBackend, auth, _, _ := newTestingEnv()
+
+	ctx := context.Background()
+	initBlock, err := etherman.EthClient.BlockByNumber(ctx, nil)
+	require.NoError(t, err)
+
+	rawTxs := "f84901843b9aca00827b0c945fbdb2315678afecb367f032d93f642f64180aa380a46057361d00000000000000000000000000000000000000000000000000000000000000048203e9808073efe1fa2d3e27f26f32208550ea9b0274d49050b816cadab05a771f4275d0242fd5d92b3fb89575c070e6c930587c520ee65a3aa8cfe382fcad20421bf51d621c"
+	tx := polygonzkevm.PolygonZkEVMBatchData{
+		GlobalExitRoot:     common.Hash{},
+		Timestamp:          initBlock.Time(),
+		MinForcedTimestamp: 0,
+		Transactions:       common.Hex2Bytes(rawTxs),
+	}
+	_, err = etherman.ZkEVM.SequenceBatches(auth, []polygonzkevm.PolygonZkEVMBatchData{tx}, auth.From)
+	require.NoError(t, err)
+
+	// Mine the tx in a block
+	ethBackend.Commit()
+
+	inputs := &ethmanTypes.FinalProofInputs{
+		FinalProof:       &pb.FinalProof{Proof: "0x"},
+		NewLocalExitRoot: common.Hash{}.Bytes(),
+		NewStateRoot:     common.Hash{}.Bytes(),
+	}
+	// the mock verifier accepts any proof from the trusted aggregator
+	err = etherman.CheckTrustedVerifyBatches(ctx, auth.From, 0, 1, inputs)
+	assert.NoError(t, err)
+
+	// the tx reverts for any other sender, which is not an invalid proof
+	err = etherman.CheckTrustedVerifyBatches(ctx, common.HexToAddress("0x1"), 0, 1, inputs)
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, ErrInvalidProof)
+}
+
 func TestSequenceForceBatchesEvent(t *testing.T) {
 	// Set up testing environment
 	etherman, ethBackend, auth, _, _ := newTestingEnv()
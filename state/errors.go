@@ -30,6 +30,9 @@ var (
 	ErrLastBatchShouldBeClosed = errors.New("last batch needs to be closed before adding a new one")
 	// ErrBatchAlreadyClosed indicates that batch is already closed
 	ErrBatchAlreadyClosed = errors.New("batch is already closed")
+	// ErrProofAlreadyClaimed indicates a proof for the same batch range is
+	// already stored, generated or being generated
+	ErrProofAlreadyClaimed = errors.New("proof already claimed")
 	// ErrClosingBatchWithoutTxs indicates that the batch attempted to close does not have txs.
 	ErrClosingBatchWithoutTxs = errors.New("can not close a batch without transactions")
 	// ErrTimestampGE indicates that timestamp needs to be greater or equal
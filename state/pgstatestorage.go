@@ -11,12 +11,17 @@ import (
 	"github.com/0xPolygonHermez/zkevm-node/hex"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/jackc/pgconn"
 	"github.com/jackc/pgx/v4"
 	"github.com/jackc/pgx/v4/pgxpool"
 )
 
 const maxTopics = 4
 
+// pgUniqueViolation is the postgres error code for a unique constraint
+// violation.
+const pgUniqueViolation = "23505"
+
 const (
 	getLastBatchNumberSQL = "SELECT batch_num FROM state.batch ORDER BY batch_num DESC LIMIT 1"
 	getLastBlockNumSQL    = "SELECT block_num FROM state.block ORDER BY block_num DESC LIMIT 1"
@@ -542,6 +547,22 @@ func (p *PostgresStorage) GetLatestVirtualBatchTimestamp(ctx context.Context, db
 	return timestamp, nil
 }
 
+// GetVirtualBatchTimestamp gets the timestamp of the L1 block in which the
+// batch was sequenced
+func (p *PostgresStorage) GetVirtualBatchTimestamp(ctx context.Context, batchNumber uint64, dbTx pgx.Tx) (time.Time, error) {
+	const getVirtualBatchTimestampSQL = `SELECT block.received_at FROM state.virtual_batch INNER JOIN state.block ON state.block.block_num = virtual_batch.block_num WHERE virtual_batch.batch_num = $1`
+	var timestamp time.Time
+	e := p.getExecQuerier(dbTx)
+	err := e.QueryRow(ctx, getVirtualBatchTimestampSQL, batchNumber).Scan(&timestamp)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return time.Unix(0, 0), ErrNotFound
+	} else if err != nil {
+		return time.Unix(0, 0), err
+	}
+	return timestamp, nil
+}
+
 // SetLastBatchNumberSeenOnEthereum sets the last batch number that affected
 // the roll-up in order to allow the components to know if the state
 // is synchronized or not
@@ -2047,6 +2068,43 @@ func (p *PostgresStorage) GetSequences(ctx context.Context, lastVerifiedBatchNum
 	return sequences, err
 }
 
+// GetVirtualBatchToProveInRange return the next batch within the batch range
+// [fromBatchNumber, toBatchNumber] that is not proved, neither in proved
+// process.
+func (p *PostgresStorage) GetVirtualBatchToProveInRange(ctx context.Context, lastVerfiedBatchNumber, fromBatchNumber, toBatchNumber uint64, dbTx pgx.Tx) (*Batch, error) {
+	const query = `
+		SELECT
+			b.batch_num,
+			b.global_exit_root,
+			b.local_exit_root,
+			b.acc_input_hash,
+			b.state_root,
+			b.timestamp,
+			b.coinbase,
+			b.raw_txs_data,
+			b.forced_batch_num
+		FROM
+			state.batch b,
+			state.virtual_batch v
+		WHERE
+			b.batch_num > $1 AND b.batch_num >= $2 AND b.batch_num <= $3 AND b.batch_num = v.batch_num AND
+			NOT EXISTS (
+				SELECT p.batch_num FROM state.proof p
+				WHERE v.batch_num >= p.batch_num AND v.batch_num <= p.batch_num_final
+			)
+		ORDER BY b.batch_num ASC LIMIT 1
+		`
+	e := p.getExecQuerier(dbTx)
+	row := e.QueryRow(ctx, query, lastVerfiedBatchNumber, fromBatchNumber, toBatchNumber)
+	batch, err := scanBatch(row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	return &batch, nil
+}
+
 // GetVirtualBatchToProve return the next batch that is not proved, neither in
 // proved process.
 func (p *PostgresStorage) GetVirtualBatchToProve(ctx context.Context, lastVerfiedBatchNumber uint64, dbTx pgx.Tx) (*Batch, error) {
@@ -2098,7 +2156,8 @@ func (p *PostgresStorage) CheckProofContainsCompleteSequences(ctx context.Contex
 	return exists, nil
 }
 
-// GetProofReadyToVerify return the proof that is ready to verify
+// GetProofReadyToVerify return the proof that is ready to verify. If several
+// proofs are ready, the one covering more batches is returned.
 func (p *PostgresStorage) GetProofReadyToVerify(ctx context.Context, lastVerfiedBatchNumber uint64, dbTx pgx.Tx) (*Proof, error) {
 	const getProofReadyToVerifySQL = `
 		SELECT 
@@ -2109,20 +2168,23 @@ func (p *PostgresStorage) GetProofReadyToVerify(ctx context.Context, lastVerfied
 			p.input_prover,
 			p.prover,
 			p.prover_id,
+			p.attempt_id,
 			p.generating_since,
 			p.created_at,
 			p.updated_at
 		FROM state.proof p
 		WHERE batch_num = $1 AND generating_since IS NULL AND
 			EXISTS (SELECT 1 FROM state.sequences s1 WHERE s1.from_batch_num = p.batch_num) AND
-			EXISTS (SELECT 1 FROM state.sequences s2 WHERE s2.to_batch_num = p.batch_num_final)		
+			EXISTS (SELECT 1 FROM state.sequences s2 WHERE s2.to_batch_num = p.batch_num_final)
+		ORDER BY p.batch_num_final DESC
+		LIMIT 1
 		`
 
 	var proof *Proof = &Proof{}
 
 	e := p.getExecQuerier(dbTx)
 	row := e.QueryRow(ctx, getProofReadyToVerifySQL, lastVerfiedBatchNumber+1)
-	err := row.Scan(&proof.BatchNumber, &proof.BatchNumberFinal, &proof.Proof, &proof.ProofID, &proof.InputProver, &proof.Prover, &proof.ProverID, &proof.GeneratingSince, &proof.CreatedAt, &proof.UpdatedAt)
+	err := row.Scan(&proof.BatchNumber, &proof.BatchNumberFinal, &proof.Proof, &proof.ProofID, &proof.InputProver, &proof.Prover, &proof.ProverID, &proof.AttemptID, &proof.GeneratingSince, &proof.CreatedAt, &proof.UpdatedAt)
 
 	if errors.Is(err, pgx.ErrNoRows) {
 		return nil, ErrNotFound
@@ -2133,8 +2195,10 @@ func (p *PostgresStorage) GetProofReadyToVerify(ctx context.Context, lastVerfied
 	return proof, err
 }
 
-// GetProofsToAggregate return the next to proof that it is possible to aggregate
-func (p *PostgresStorage) GetProofsToAggregate(ctx context.Context, dbTx pgx.Tx) (*Proof, *Proof, error) {
+// GetProofsToAggregate return the next to proof that it is possible to aggregate,
+// starting at fromBatchNumber. The pairs whose aggregation would span more than
+// maxBatches batches are not returned, 0 means no limit.
+func (p *PostgresStorage) GetProofsToAggregate(ctx context.Context, fromBatchNumber, maxBatches uint64, dbTx pgx.Tx) (*Proof, *Proof, error) {
 	var (
 		proof1 *Proof = &Proof{}
 		proof2 *Proof = &Proof{}
@@ -2150,6 +2214,7 @@ func (p *PostgresStorage) GetProofsToAggregate(ctx context.Context, dbTx pgx.Tx)
 			p1.input_prover as p1_input_prover, 
 			p1.prover as p1_prover,
 			p1.prover_id as p1_prover_id,
+			p1.attempt_id as p1_attempt_id,
 			p1.generating_since as p1_generating_since,
 			p1.created_at as p1_created_at,
 			p1.updated_at as p1_updated_at,
@@ -2160,11 +2225,14 @@ func (p *PostgresStorage) GetProofsToAggregate(ctx context.Context, dbTx pgx.Tx)
 			p2.input_prover as p2_input_prover, 
 			p2.prover as p2_prover,
 			p2.prover_id as p2_prover_id,
+			p2.attempt_id as p2_attempt_id,
 			p2.generating_since as p2_generating_since,
 			p2.created_at as p2_created_at,
 			p2.updated_at as p2_updated_at
 		FROM state.proof p1 INNER JOIN state.proof p2 ON p1.batch_num_final = p2.batch_num - 1
-		WHERE p1.generating_since IS NULL AND p2.generating_since IS NULL AND 
+		WHERE p1.batch_num >= $1 AND
+			  ($2::BIGINT = 0 OR p2.batch_num_final - p1.batch_num + 1 <= $2::BIGINT) AND
+			  p1.generating_since IS NULL AND p2.generating_since IS NULL AND 
 		 	  p1.proof IS NOT NULL AND p2.proof IS NOT NULL AND
 			  (
 					EXISTS (
@@ -2187,10 +2255,10 @@ func (p *PostgresStorage) GetProofsToAggregate(ctx context.Context, dbTx pgx.Tx)
 		`
 
 	e := p.getExecQuerier(dbTx)
-	row := e.QueryRow(ctx, getProofsToAggregateSQL)
+	row := e.QueryRow(ctx, getProofsToAggregateSQL, fromBatchNumber, maxBatches)
 	err := row.Scan(
-		&proof1.BatchNumber, &proof1.BatchNumberFinal, &proof1.Proof, &proof1.ProofID, &proof1.InputProver, &proof1.Prover, &proof1.ProverID, &proof1.GeneratingSince, &proof1.CreatedAt, &proof1.UpdatedAt,
-		&proof2.BatchNumber, &proof2.BatchNumberFinal, &proof2.Proof, &proof2.ProofID, &proof2.InputProver, &proof2.Prover, &proof2.ProverID, &proof2.GeneratingSince, &proof2.CreatedAt, &proof2.UpdatedAt)
+		&proof1.BatchNumber, &proof1.BatchNumberFinal, &proof1.Proof, &proof1.ProofID, &proof1.InputProver, &proof1.Prover, &proof1.ProverID, &proof1.AttemptID, &proof1.GeneratingSince, &proof1.CreatedAt, &proof1.UpdatedAt,
+		&proof2.BatchNumber, &proof2.BatchNumberFinal, &proof2.Proof, &proof2.ProofID, &proof2.InputProver, &proof2.Prover, &proof2.ProverID, &proof2.AttemptID, &proof2.GeneratingSince, &proof2.CreatedAt, &proof2.UpdatedAt)
 
 	if errors.Is(err, pgx.ErrNoRows) {
 		return nil, nil, ErrNotFound
@@ -2201,21 +2269,26 @@ func (p *PostgresStorage) GetProofsToAggregate(ctx context.Context, dbTx pgx.Tx)
 	return proof1, proof2, err
 }
 
-// AddGeneratedProof adds a generated proof to the storage
+// AddGeneratedProof adds a generated proof to the storage, returning
+// ErrProofAlreadyClaimed if a proof for the same batch range is already stored.
 func (p *PostgresStorage) AddGeneratedProof(ctx context.Context, proof *Proof, dbTx pgx.Tx) error {
-	const addGeneratedProofSQL = "INSERT INTO state.proof (batch_num, batch_num_final, proof, proof_id, input_prover, prover, prover_id, generating_since, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)"
+	const addGeneratedProofSQL = "INSERT INTO state.proof (batch_num, batch_num_final, proof, proof_id, input_prover, prover, prover_id, attempt_id, generating_since, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)"
 	e := p.getExecQuerier(dbTx)
 	now := time.Now().UTC().Round(time.Microsecond)
-	_, err := e.Exec(ctx, addGeneratedProofSQL, proof.BatchNumber, proof.BatchNumberFinal, proof.Proof, proof.ProofID, proof.InputProver, proof.Prover, proof.ProverID, proof.GeneratingSince, now, now)
+	_, err := e.Exec(ctx, addGeneratedProofSQL, proof.BatchNumber, proof.BatchNumberFinal, proof.Proof, proof.ProofID, proof.InputProver, proof.Prover, proof.ProverID, proof.AttemptID, proof.GeneratingSince, now, now)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == pgUniqueViolation {
+		return ErrProofAlreadyClaimed
+	}
 	return err
 }
 
 // UpdateGeneratedProof updates a generated proof in the storage
 func (p *PostgresStorage) UpdateGeneratedProof(ctx context.Context, proof *Proof, dbTx pgx.Tx) error {
-	const addGeneratedProofSQL = "UPDATE state.proof SET proof = $3, proof_id = $4, input_prover = $5, prover = $6, prover_id = $7, generating_since = $8, updated_at = $9 WHERE batch_num = $1 AND batch_num_final = $2"
+	const addGeneratedProofSQL = "UPDATE state.proof SET proof = $3, proof_id = $4, input_prover = $5, prover = $6, prover_id = $7, attempt_id = $8, generating_since = $9, updated_at = $10 WHERE batch_num = $1 AND batch_num_final = $2"
 	e := p.getExecQuerier(dbTx)
 	now := time.Now().UTC().Round(time.Microsecond)
-	_, err := e.Exec(ctx, addGeneratedProofSQL, proof.BatchNumber, proof.BatchNumberFinal, proof.Proof, proof.ProofID, proof.InputProver, proof.Prover, proof.ProverID, proof.GeneratingSince, now)
+	_, err := e.Exec(ctx, addGeneratedProofSQL, proof.BatchNumber, proof.BatchNumberFinal, proof.Proof, proof.ProofID, proof.InputProver, proof.Prover, proof.ProverID, proof.AttemptID, proof.GeneratingSince, now)
 	return err
 }
 
@@ -2253,6 +2326,72 @@ func (p *PostgresStorage) CleanupLockedProofs(ctx context.Context, duration stri
 	return ct.RowsAffected(), nil
 }
 
+// ListProofs returns a page of at most limit proofs, ordered by batch number
+// and final batch number, starting at the proof (fromBatch, fromBatchFinal)
+// inclusive. The next page starts at the BatchNumber and BatchNumberFinal+1 of
+// the last proof returned.
+func (p *PostgresStorage) ListProofs(ctx context.Context, fromBatch, fromBatchFinal, limit uint64, dbTx pgx.Tx) ([]*Proof, error) {
+	const listProofsSQL = `
+		SELECT batch_num, batch_num_final, proof, proof_id, input_prover, prover, prover_id, attempt_id, generating_since, created_at, updated_at
+		  FROM state.proof
+		 WHERE (batch_num, batch_num_final) >= ($1, $2)
+		 ORDER BY batch_num ASC, batch_num_final ASC
+		 LIMIT $3`
+
+	e := p.getExecQuerier(dbTx)
+	rows, err := e.Query(ctx, listProofsSQL, fromBatch, fromBatchFinal, limit)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return []*Proof{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	proofs := make([]*Proof, 0, len(rows.RawValues()))
+	for rows.Next() {
+		var proof Proof
+		err := rows.Scan(&proof.BatchNumber, &proof.BatchNumberFinal, &proof.Proof, &proof.ProofID, &proof.InputProver, &proof.Prover, &proof.ProverID, &proof.AttemptID, &proof.GeneratingSince, &proof.CreatedAt, &proof.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		proofs = append(proofs, &proof)
+	}
+
+	return proofs, nil
+}
+
+// GetGeneratingProofs returns the proofs in generating state for more than the
+// provided duration, ordered by batch number. A zero duration returns all the
+// proofs in generating state.
+func (p *PostgresStorage) GetGeneratingProofs(ctx context.Context, olderThan time.Duration, dbTx pgx.Tx) ([]*Proof, error) {
+	const getGeneratingProofsSQL = `
+		SELECT batch_num, batch_num_final, proof, proof_id, input_prover, prover, prover_id, attempt_id, generating_since, created_at, updated_at
+		  FROM state.proof
+		 WHERE generating_since IS NOT NULL AND generating_since <= NOW() - make_interval(secs => $1)
+		 ORDER BY batch_num ASC, batch_num_final ASC`
+
+	e := p.getExecQuerier(dbTx)
+	rows, err := e.Query(ctx, getGeneratingProofsSQL, olderThan.Seconds())
+	if errors.Is(err, pgx.ErrNoRows) {
+		return []*Proof{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	proofs := make([]*Proof, 0, len(rows.RawValues()))
+	for rows.Next() {
+		var proof Proof
+		err := rows.Scan(&proof.BatchNumber, &proof.BatchNumberFinal, &proof.Proof, &proof.ProofID, &proof.InputProver, &proof.Prover, &proof.ProverID, &proof.AttemptID, &proof.GeneratingSince, &proof.CreatedAt, &proof.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		proofs = append(proofs, &proof)
+	}
+
+	return proofs, nil
+}
+
 // DeleteUngeneratedProofs deletes ungenerated proofs.
 // This method is meant to be use during aggregator boot-up sequence
 func (p *PostgresStorage) DeleteUngeneratedProofs(ctx context.Context, dbTx pgx.Tx) error {
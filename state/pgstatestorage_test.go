@@ -397,6 +397,183 @@ func TestCleanupLockedProofs(t *testing.T) {
 	assert.Contains(proofs, newerProof)
 }
 
+func TestGetGeneratingProofs(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+	initOrResetDB()
+	ctx := context.Background()
+	batchNumber := uint64(42)
+	_, err = testState.PostgresStorage.Exec(ctx, "INSERT INTO state.batch (batch_num) VALUES ($1), ($2), ($3)", batchNumber, batchNumber+1, batchNumber+2)
+	require.NoError(err)
+	now := time.Now().Round(time.Microsecond)
+	oneHourAgo := now.Add(-time.Hour).Round(time.Microsecond)
+	// proof locked for an hour
+	olderProof := state.Proof{
+		BatchNumber:      batchNumber,
+		BatchNumberFinal: batchNumber,
+		GeneratingSince:  &oneHourAgo,
+	}
+	// proof locked right now
+	newerProof := state.Proof{
+		BatchNumber:      batchNumber + 1,
+		BatchNumberFinal: batchNumber + 1,
+		GeneratingSince:  &now,
+	}
+	// proof not locked (currently not generating)
+	notGenProof := state.Proof{
+		BatchNumber:      batchNumber + 2,
+		BatchNumberFinal: batchNumber + 2,
+	}
+	for _, proof := range []state.Proof{olderProof, newerProof, notGenProof} {
+		proof := proof
+		require.NoError(testState.AddGeneratedProof(ctx, &proof, nil))
+	}
+
+	proofs, err := testState.GetGeneratingProofs(ctx, time.Minute, nil)
+	require.NoError(err)
+	require.Len(proofs, 1)
+	assert.Equal(olderProof.BatchNumber, proofs[0].BatchNumber)
+	assert.Equal(oneHourAgo.Unix(), proofs[0].GeneratingSince.Unix())
+
+	proofs, err = testState.GetGeneratingProofs(ctx, 0, nil)
+	require.NoError(err)
+	require.Len(proofs, 2)
+	assert.Equal(olderProof.BatchNumber, proofs[0].BatchNumber)
+	assert.Equal(newerProof.BatchNumber, proofs[1].BatchNumber)
+}
+
+func TestListProofs(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+	initOrResetDB()
+	ctx := context.Background()
+	_, err = testState.PostgresStorage.Exec(ctx, "INSERT INTO state.batch (batch_num) VALUES (1), (2), (3), (4), (5)")
+	require.NoError(err)
+	// several proofs share the same starting batch
+	ranges := [][2]uint64{{1, 1}, {1, 2}, {1, 3}, {2, 2}, {3, 5}, {4, 4}, {5, 5}}
+	for _, r := range ranges {
+		require.NoError(testState.AddGeneratedProof(ctx, &state.Proof{BatchNumber: r[0], BatchNumberFinal: r[1]}, nil))
+	}
+
+	const limit = 2
+	var listed [][2]uint64
+	var fromBatch, fromBatchFinal uint64
+	for {
+		proofs, err := testState.ListProofs(ctx, fromBatch, fromBatchFinal, limit, nil)
+		require.NoError(err)
+		require.LessOrEqual(len(proofs), limit)
+		for _, proof := range proofs {
+			listed = append(listed, [2]uint64{proof.BatchNumber, proof.BatchNumberFinal})
+		}
+		if len(proofs) < limit {
+			break
+		}
+		last := proofs[len(proofs)-1]
+		fromBatch, fromBatchFinal = last.BatchNumber, last.BatchNumberFinal+1
+	}
+
+	assert.Equal(ranges, listed)
+}
+
+func TestGetProofReadyToVerifyPrefersLongestProof(t *testing.T) {
+	require := require.New(t)
+	initOrResetDB()
+	ctx := context.Background()
+	_, err = testState.PostgresStorage.Exec(ctx, "INSERT INTO state.batch (batch_num) VALUES (1), (2), (3), (4)")
+	require.NoError(err)
+	require.NoError(testState.AddSequence(ctx, state.Sequence{FromBatchNumber: 1, ToBatchNumber: 2}, nil))
+	require.NoError(testState.AddSequence(ctx, state.Sequence{FromBatchNumber: 3, ToBatchNumber: 4}, nil))
+	// both proofs start at the next batch to verify and cover complete
+	// sequences
+	require.NoError(testState.AddGeneratedProof(ctx, &state.Proof{BatchNumber: 1, BatchNumberFinal: 2}, nil))
+	require.NoError(testState.AddGeneratedProof(ctx, &state.Proof{BatchNumber: 1, BatchNumberFinal: 4}, nil))
+
+	proof, err := testState.GetProofReadyToVerify(ctx, 0, nil)
+
+	require.NoError(err)
+	assert.Equal(t, uint64(1), proof.BatchNumber)
+	assert.Equal(t, uint64(4), proof.BatchNumberFinal)
+}
+
+func TestGetProofsToAggregateFromBatchNumber(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+	initOrResetDB()
+	ctx := context.Background()
+	_, err = testState.PostgresStorage.Exec(ctx, "INSERT INTO state.batch (batch_num) VALUES (1), (2), (3), (4)")
+	require.NoError(err)
+	require.NoError(testState.AddSequence(ctx, state.Sequence{FromBatchNumber: 1, ToBatchNumber: 4}, nil))
+	for batchNum := uint64(1); batchNum <= 4; batchNum++ {
+		require.NoError(testState.AddGeneratedProof(ctx, &state.Proof{BatchNumber: batchNum, BatchNumberFinal: batchNum}, nil))
+	}
+
+	proof1, proof2, err := testState.GetProofsToAggregate(ctx, 0, 0, nil)
+	require.NoError(err)
+	assert.Equal(uint64(1), proof1.BatchNumber)
+	assert.Equal(uint64(2), proof2.BatchNumber)
+
+	proof1, proof2, err = testState.GetProofsToAggregate(ctx, 2, 0, nil)
+	require.NoError(err)
+	assert.Equal(uint64(2), proof1.BatchNumber)
+	assert.Equal(uint64(3), proof2.BatchNumber)
+
+	_, _, err = testState.GetProofsToAggregate(ctx, 4, 0, nil)
+	assert.ErrorIs(err, state.ErrNotFound)
+}
+
+func TestGetProofsToAggregateMaxBatches(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+	initOrResetDB()
+	ctx := context.Background()
+	_, err = testState.PostgresStorage.Exec(ctx, "INSERT INTO state.batch (batch_num) VALUES (1), (2), (3), (4)")
+	require.NoError(err)
+	require.NoError(testState.AddSequence(ctx, state.Sequence{FromBatchNumber: 1, ToBatchNumber: 4}, nil))
+	require.NoError(testState.AddGeneratedProof(ctx, &state.Proof{BatchNumber: 1, BatchNumberFinal: 2}, nil))
+	require.NoError(testState.AddGeneratedProof(ctx, &state.Proof{BatchNumber: 3, BatchNumberFinal: 4}, nil))
+
+	_, _, err = testState.GetProofsToAggregate(ctx, 0, 3, nil)
+	assert.ErrorIs(err, state.ErrNotFound)
+
+	proof1, proof2, err := testState.GetProofsToAggregate(ctx, 0, 4, nil)
+	require.NoError(err)
+	assert.Equal(uint64(1), proof1.BatchNumber)
+	assert.Equal(uint64(4), proof2.BatchNumberFinal)
+}
+
+func TestAddGeneratedProofAlreadyClaimed(t *testing.T) {
+	initOrResetDB()
+	ctx := context.Background()
+	_, err := testState.PostgresStorage.Exec(ctx, "INSERT INTO state.batch (batch_num) VALUES (1)")
+	require.NoError(t, err)
+	require.NoError(t, testState.AddGeneratedProof(ctx, &state.Proof{BatchNumber: 1, BatchNumberFinal: 1}, nil))
+
+	err = testState.AddGeneratedProof(ctx, &state.Proof{BatchNumber: 1, BatchNumberFinal: 1}, nil)
+
+	assert.ErrorIs(t, err, state.ErrProofAlreadyClaimed)
+}
+
+func TestGetVirtualBatchTimestamp(t *testing.T) {
+	require := require.New(t)
+	initOrResetDB()
+	ctx := context.Background()
+	dbTx, err := testState.BeginStateTransaction(ctx)
+	require.NoError(err)
+	defer func() { require.NoError(dbTx.Commit(ctx)) }()
+	receivedAt := time.Unix(1700000000, 0)
+	require.NoError(testState.AddBlock(ctx, &state.Block{BlockNumber: 1, ReceivedAt: receivedAt}, dbTx))
+	_, err = testState.PostgresStorage.Exec(ctx, "INSERT INTO state.batch (batch_num) VALUES (1)")
+	require.NoError(err)
+	require.NoError(testState.AddVirtualBatch(ctx, &state.VirtualBatch{BatchNumber: 1, BlockNumber: 1}, dbTx))
+
+	timestamp, err := testState.GetVirtualBatchTimestamp(ctx, 1, dbTx)
+	require.NoError(err)
+	assert.True(t, receivedAt.Equal(timestamp))
+
+	_, err = testState.GetVirtualBatchTimestamp(ctx, 2, dbTx)
+	assert.ErrorIs(t, err, state.ErrNotFound)
+}
+
 func TestVirtualBatch(t *testing.T) {
 	initOrResetDB()
 
@@ -13,6 +13,9 @@ type Proof struct {
 	Prover *string
 	// ProverID prover process identifier.
 	ProverID *string
+	// AttemptID identifies the aggregator attempt that generated the proof,
+	// to correlate it across the aggregator and prover logs.
+	AttemptID *string
 	// GeneratingSince holds the timestamp for the moment in which the
 	// proof generation has started by a prover. Nil if the proof is not
 	// currently generating.